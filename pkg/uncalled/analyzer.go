@@ -7,10 +7,13 @@ import (
 	"go/ast"
 	"go/types"
 	"os"
+	"strings"
 
 	"github.com/rs/zerolog"
 	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
 	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/go/ast/inspector"
 )
 
@@ -37,6 +40,10 @@ This helps uncover errors which will result in incomplete data if an
 error is triggered while processing rows. This can happen when a
 connection becomes invalid, this causes Rows.Next() to return
 false without processing all rows.`
+
+	// resultCategory is the category used to report expected calls
+	// whose result must be checked but was ignored.
+	resultCategory = "result"
 )
 
 // Option represents an Analyzer option.
@@ -52,10 +59,21 @@ func ConfigFile(file string) Option {
 }
 
 // ConfigOpt is an Analyzer option which merges in cfg to our default config.
+// cfg isn't modified, so it can be shared by concurrent passes.
 // Default: embedded config.
 func ConfigOpt(cfg *Config) Option {
 	return func(a *analyzer) error {
-		return a.cfg.merge(cfg)
+		if cfg == nil {
+			return nil
+		}
+
+		// Take a copy as passes build and resolve their rules.
+		other, err := cfg.copy()
+		if err != nil {
+			return err
+		}
+
+		return a.cfg.merge(other)
 	}
 }
 
@@ -74,6 +92,58 @@ func LogLevel(level string) Option {
 	}
 }
 
+// UnusedIgnores is an Analyzer option which configures it to report
+// ignore directives which don't suppress anything.
+// Default: false.
+func UnusedIgnores(enable bool) Option {
+	return func(a *analyzer) error {
+		a.unusedIgnores = enable
+		return nil
+	}
+}
+
+// Baseline is an Analyzer option which suppresses findings recorded in
+// the baseline file, as written by WriteBaseline.
+// Analyzers created by NewAnalyzer share the baseline across passes, so
+// each recorded finding only suppresses one report.
+// Default: no baseline.
+func Baseline(file string) Option {
+	b, err := loadBaseline(file)
+	return func(a *analyzer) error {
+		if err != nil {
+			return err
+		}
+
+		a.baseline = b
+		return nil
+	}
+}
+
+// WriteBaseline is an Analyzer option which records findings to the
+// baseline file, which is written once all packages are checked.
+// Default: no baseline written.
+func WriteBaseline(file string) Option {
+	b := createBaseline(file)
+	return func(a *analyzer) error {
+		a.record = b
+		return nil
+	}
+}
+
+// baselines is an Analyzer option which sets the baseline to check
+// against and the baseline to record to, either may be nil.
+func baselines(check, record *baseline) Option {
+	return func(a *analyzer) error {
+		if check != nil {
+			a.baseline = check
+		}
+		if record != nil {
+			a.record = record
+		}
+		return nil
+	}
+}
+
 // testWriter is an Analyzer option which configures its log to use t
 // and sets its log level to debug.
 // Default: os.Stderr.
@@ -113,8 +183,14 @@ func NewAnalyzer(options ...Option) *analysis.Analyzer {
 		Name: name,
 		Doc:  doc,
 		Run:  l.run,
+		FactTypes: []analysis.Fact{
+			(*releasesFact)(nil),
+			(*acquiresFact)(nil),
+			(*ownsFact)(nil),
+		},
 		Requires: []*analysis.Analyzer{
 			inspect.Analyzer,
+			ctrlflow.Analyzer,
 		},
 	}
 
@@ -122,15 +198,49 @@ func NewAnalyzer(options ...Option) *analysis.Analyzer {
 	a.Flags.Var(l, "config", "configuration file to load")
 	a.Flags.Var(version{}, "version", "print version and exit")
 	a.Flags.Var(&l.log, "verbose", "increases the log level")
+	a.Flags.BoolVar(&l.unusedIgnores, "unused-ignores", false, "report ignore directives which don't suppress anything")
+	a.Flags.Func("baseline", "suppress findings recorded in baseline `file`", l.setBaseline)
+	a.Flags.Func("write-baseline", "record findings to baseline `file`", l.setWriteBaseline)
+	a.Flags.Func("format", "output `format`, one of text or sarif (default text)", l.setFormat)
+	a.Flags.StringVar(&l.output, "o", "", "`file` to write the report to, required by the sarif format")
 
 	return a
 }
 
 // analyzer checks for missing calls.
 type analyzer struct {
-	pass *analysis.Pass
-	cfg  *Config
-	log  zerolog.Logger
+	pass  *analysis.Pass
+	cfgs  *ctrlflow.CFGs
+	facts *facts
+	cfg   *Config
+	log   zerolog.Logger
+
+	// directives are the suppression directives of the current package.
+	directives []*directive
+
+	// unusedIgnores enables reporting of directives which don't
+	// suppress anything.
+	unusedIgnores bool
+
+	// baseline contains the findings to suppress, nil if none.
+	baseline *baseline
+
+	// record is the baseline findings are recorded to, nil if none.
+	record *baseline
+
+	// found are the findings of the current package to record.
+	found []finding
+
+	// sarif is the SARIF report to add results to, nil if none.
+	sarif *sarifReport
+
+	// results are the SARIF results of the current package.
+	results []sarifResult
+
+	// root is true if the current package is below the working directory,
+	// rather than a dependency analysed only to compute facts for its
+	// importers.
+	root bool
 }
 
 // newAnalyzer returns a new analyzer with options configured.
@@ -140,7 +250,7 @@ func newAnalyzer(options ...Option) (*analyzer, error) {
 		return nil, err
 	}
 
-	a := &analyzer{cfg: cfg}
+	a := &analyzer{cfg: cfg, root: true}
 	for _, f := range options {
 		if err := f(a); err != nil {
 			return nil, err
@@ -150,16 +260,14 @@ func newAnalyzer(options ...Option) (*analyzer, error) {
 	return a, nil
 }
 
-// buildConfig builds the configuration for imports and
-// returns true if there are active rules, false otherwise.
+// buildConfig builds the configuration for imports and the types used
+// by the current package and returns true if there are active rules,
+// false otherwise.
 func (a *analyzer) buildConfig(imports []*types.Package) bool {
 	// Check if we import one of checked packages.
-	paths := make(map[string]struct{}, len(imports))
-	pathList := make([]string, len(imports))
+	paths := make([]string, len(imports))
 	for i, imp := range imports {
-		p := imp.Path()
-		pathList[i] = p
-		paths[p] = struct{}{}
+		paths[i] = imp.Path()
 	}
 
 	rules := make([]Rule, 0, len(a.cfg.active))
@@ -167,12 +275,21 @@ func (a *analyzer) buildConfig(imports []*types.Package) bool {
 		rules = append(rules, rule)
 	}
 
+	var used []string
 	active := make([]string, 0, len(a.cfg.active))
 	for _, rule := range rules {
 		j := 0
 		for _, p := range rule.Packages {
-			if _, ok := paths[p]; !ok {
-				continue // Package wasn't imported.
+			if !matchesAny(p, paths) {
+				// Not imported directly, but its types can still be used
+				// via another package, for example a repository wrapper.
+				if used == nil {
+					used = typePackages(a.pass.TypesInfo)
+				}
+
+				if !matchesAny(p, used) {
+					continue // Package types aren't used.
+				}
 			}
 			rule.Packages[j] = p
 			j++
@@ -186,10 +303,18 @@ func (a *analyzer) buildConfig(imports []*types.Package) bool {
 			delete(a.cfg.active, rule.Name)
 			continue
 		}
+
+		if !rule.resolve(a.pass.Pkg) {
+			a.log.Debug().
+				Str("rule", rule.Name).
+				Msg("skip interface not found")
+			delete(a.cfg.active, rule.Name)
+			continue
+		}
 		active = append(active, rule.Name)
 	}
 
-	a.log.Debug().Strs("imports", pathList).Msg("imports")
+	a.log.Debug().Strs("imports", paths).Msg("imports")
 	a.log.Debug().Strs("rules", active).Msg("active")
 	a.log.Trace().Msgf("config\n%s", a.cfg.string())
 
@@ -209,16 +334,42 @@ func (a *analyzer) run(pass *analysis.Pass) (interface{}, error) {
 		pkgs[imp.Path()] = struct{}{}
 	}
 
-	if !a.buildConfig(pass.Pkg.Imports()) {
+	a.pass = pass
+	a.directives = a.parseDirectives()
+	a.check()
+	if a.unusedIgnores {
+		a.reportUnusedIgnores()
+	}
+
+	a.flush()
+
+	return nil, nil
+}
+
+// check checks the current package against the active rules.
+func (a *analyzer) check() {
+	if !a.buildConfig(a.pass.Pkg.Imports()) {
 		// No rules left so no need to check.
-		return nil, nil //nolint: nilnil
+		return
 	}
 
-	a.pass = pass
-	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector) //nolint: forcetypeassert
+	a.cfgs = a.pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs) //nolint: forcetypeassert
+	a.facts = newFacts(a.pass, a.cfgs, a.log, a.cfg.active, a.cfg.ownerMethod)
+	a.facts.export()
+	ins := a.pass.ResultOf[inspect.Analyzer].(*inspector.Inspector) //nolint: forcetypeassert
 	ins.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, a.visit)
+}
 
-	return nil, nil //nolint: nilnil
+// flush adds the findings of the current package to the baseline and
+// SARIF report if configured, which are written once the run finishes.
+func (a *analyzer) flush() {
+	if a.record != nil {
+		a.record.add(a.found)
+	}
+
+	if a.sarif != nil {
+		a.sarif.add(a.results)
+	}
 }
 
 // visit evaluates node to ensure checking each rule.
@@ -229,36 +380,23 @@ func (a *analyzer) visit(node ast.Node, push bool, stack []ast.Node) bool {
 	}
 
 	call := node.(*ast.CallExpr) //nolint: forcetypeassert
-	sel, ok := call.Fun.(*ast.SelectorExpr)
-	if !ok {
-		return true
-	}
-
-	typ, ok := a.pass.TypesInfo.Types[sel]
-	if !ok {
-		return true // Unknown type.
-	}
-
-	sig, ok := typ.Type.(*types.Signature)
-	if !ok {
-		return true // Call is not of the form x.f().
-	}
-
-	for _, rule := range a.cfg.active {
-		a.checkRule(rule, call, sig, stack)
+	for _, r := range a.cfg.active {
+		for _, rule := range r.obligations() {
+			a.checkRule(rule, call, stack)
+		}
 	}
 
 	return true
 }
 
 // checkRule checks rule against given call.
-func (a *analyzer) checkRule(rule Rule, call *ast.CallExpr, sig *types.Signature, stack []ast.Node) {
-	match := rule.matchesResults(sig.Results())
+func (a *analyzer) checkRule(rule Rule, call *ast.CallExpr, stack []ast.Node) {
+	acq, match := a.facts.trigger(rule, call)
 	a.log.Debug().
 		Str("rule", rule.Name).
-		Stringer("sig", sig).
+		Str("call", types.ExprString(call.Fun)).
 		Bool("match", match).
-		Msg("matchesResults")
+		Msg("trigger")
 	if !match {
 		return // Function call is not related to this rule.
 	}
@@ -266,48 +404,369 @@ func (a *analyzer) checkRule(rule Rule, call *ast.CallExpr, sig *types.Signature
 	// Find the innermost containing block, and get the list
 	// of statements starting with the one containing call.
 	stmts := restOfBlock(stack)
-	stmt, ok := stmts[0].(*ast.AssignStmt)
-	if !ok {
-		// First statement is not assignment so not called.
+	if init := initStmt(stack); init != nil {
+		// Acquired by the init statement of an if, switch or for, whose
+		// flow the control-flow graph already includes.
+		stmts = []ast.Stmt{init}
+	}
+
+	if rule.expects.arg {
+		a.checkArg(rule, call, stmts, stack)
+		return
+	}
+
+	if len(stmts) == 0 {
+		if spec := valueSpec(stack); spec != nil {
+			// Package level var declaration.
+			a.checkPackageVar(rule, acq, spec, call)
+			return
+		}
+
+		// Not in a statement list, for example a case expression.
+		a.log.Debug().Msg("no containing statement")
+		return
+	}
+
+	switch stmt := stmts[0].(type) {
+	case *ast.ReturnStmt:
+		if returns(stmt, call) {
+			// Returned to the caller which takes over the obligation.
+			a.log.Debug().Msg("returned")
+			return
+		}
+	case *ast.AssignStmt:
+		if node := assigned(stmt, call, acq.Result); node != nil {
+			a.checkAssign(rule, acq, call, stmt, node, stack)
+			return
+		}
+	case *ast.DeclStmt:
+		if spec := valueSpec(stack); spec != nil {
+			if node := assigned(assignment(spec), call, acq.Result); node != nil {
+				a.checkAssign(rule, acq, call, spec, node, stack)
+				return
+			}
+		}
+	}
+
+	// Result is not assigned so not called.
+	a.log.Debug().Msg("return not assigned")
+	a.report(call, rule, "", nil)
+}
+
+// checkAssign checks rule against the result of acq from call assigned
+// to node by start, an assignment or var declaration spec.
+func (a *analyzer) checkAssign(rule Rule, acq acquired, call *ast.CallExpr, start ast.Node, node ast.Expr, stack []ast.Node) {
+	if st, ok := fieldStore(a.pass.TypesInfo, node); ok {
+		// Assigned directly to a field.
+		a.checkStore(rule, st)
+		return
+	}
+
+	ident := rootIdent(node)
+	if ident == nil {
+		a.log.Error().Msgf("node %#v: nil root", node)
+		return // Not matching.
+	}
+
+	f := a.flow(rule, ident, stack)
+	if f == nil {
+		a.log.Debug().Msg("no enclosing function")
+		return
+	}
+
+	assign := assignment(start)
+	f.visitor.references(assign)
+	f.visitor.arguments(call)
+	if acq.IfNil >= 0 && acq.IfNil < len(assign.Lhs) && len(assign.Rhs) == 1 {
+		f.guarded(rootIdent(assign.Lhs[acq.IfNil]))
+	}
+
+	a.checkLeaks(rule, f, ident, start, assign, stack)
+}
+
+// checkArg checks rule against the argument of call which carries its
+// obligation, stmts starts with the statement containing call.
+func (a *analyzer) checkArg(rule Rule, call *ast.CallExpr, stmts []ast.Stmt, stack []ast.Node) {
+	if len(stmts) == 0 || rule.expects.idx >= len(call.Args) {
+		a.log.Debug().Msg("no containing statement or arg")
+		return
+	}
+
+	ident, ok := astutil.Unparen(call.Args[rule.expects.idx]).(*ast.Ident)
+	if !ok || a.pass.TypesInfo.ObjectOf(ident) == nil {
+		a.log.Debug().Msg("arg not a variable")
+		return
+	}
+
+	f := a.flow(rule, ident, stack)
+	if f == nil {
+		a.log.Debug().Msg("no enclosing function")
+		return
+	}
+
+	f.visitor.arguments(call)
+	a.checkLeaks(rule, f, ident, stmts[0], nil, stack)
+}
+
+// checkLeaks checks the flow f for ident from start, reporting the paths
+// on which rule isn't called and the stores of ident which aren't released.
+// Fixes are suggested if ident was assigned by assign.
+func (a *analyzer) checkLeaks(rule Rule, f *flow, ident *ast.Ident, start ast.Node, assign *ast.AssignStmt, stack []ast.Node) {
+	exits := f.leaks(start)
+	seen := make(map[ast.Node]struct{}, len(f.visitor.stores))
+	for _, st := range f.visitor.stores {
+		if _, ok := seen[st.node]; ok {
+			continue // Already checked on another path.
+		}
+		seen[st.node] = struct{}{}
+		a.checkStore(rule, st)
+	}
+
+	if len(exits) == 0 {
+		return // Called on every path.
+	}
+
+	var missing []exit
+	var misses []*miss
+	seen = make(map[ast.Node]struct{}, len(exits))
+	for _, e := range exits {
+		if e.missed == nil {
+			missing = append(missing, e)
+			continue
+		}
+
+		if _, ok := seen[e.missed.call]; !ok {
+			// Called but didn't count.
+			seen[e.missed.call] = struct{}{}
+			misses = append(misses, e.missed)
+		}
+	}
+
+	a.reportMisses(rule, misses)
+	if len(missing) == 0 {
+		return
+	}
+
+	var fixes []analysis.SuggestedFix
+	if assign != nil {
+		fixes = a.fixes(rule, f, ident, assign, stack)
+	}
+	a.report(ident, rule, ident.Name, fixes, missing...)
+}
+
+// checkPackageVar checks rule against the result of acq assigned to a
+// package level variable by spec, which must be called by a function of
+// the package, such as init.
+func (a *analyzer) checkPackageVar(rule Rule, acq acquired, spec *ast.ValueSpec, call *ast.CallExpr) {
+	assign := assignment(spec)
+	node := assigned(assign, call, acq.Result)
+	if node == nil {
 		a.log.Debug().Msg("return not assigned")
-		a.report(call, rule, "")
+		a.report(call, rule, "", nil)
 		return
 	}
 
-	node := stmt.Lhs[rule.expects.idx]
 	ident := rootIdent(node)
 	if ident == nil {
 		a.log.Error().Msgf("node %#v: nil root", node)
 		return // Not matching.
 	}
 
-	if len(stmts) < 2 {
-		// Call to the sql function is the last statement of the block.
-		a.log.Debug().Msg("no statements")
-		a.report(ident, rule, ident.Name)
+	v := newVisitor(a.pass, a.log, a.facts, rule, ident)
+	v.references(assign)
+	v.arguments(call)
+	for _, file := range a.pass.Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil && v.walk(fn.Body) {
+				return // Called by a function of the package.
+			}
+		}
+	}
+
+	a.report(ident, rule, ident.Name, nil)
+}
+
+// checkStore checks that the owner of the field in st releases it,
+// reporting the store if not.
+func (a *analyzer) checkStore(rule Rule, st store) {
+	if a.ownerReleases(rule, st.owner, st.field.Name()) {
 		return
 	}
 
-	// TODO(steve): avoid multiple passes.
-	for _, rule := range a.cfg.active {
-		if visit(a.pass, a.log, rule, ident, stmts[1:]) {
+	owner := st.owner.Obj()
+	name := rule.name(owner.Name() + "." + st.field.Name())
+	methods := a.cfg.OwnerMethods
+	var method string
+	switch len(methods) {
+	case 0:
+		method = "an owner"
+	case 1:
+		method = "a " + methods[0]
+	default:
+		method = "a " + strings.Join(methods[:len(methods)-1], ", ") + " or " + methods[len(methods)-1]
+	}
+
+	a.log.Debug().
+		Str("rule", rule.Name).
+		Str("name", name).
+		Msg("field not released")
+	a.emit(rule, analysis.Diagnostic{
+		Pos:      st.node.Pos(),
+		End:      st.node.End(),
+		Category: rule.Category,
+		Message:  fmt.Sprintf("%s must be called by %s method of %s", name, method, owner.Name()),
+		Related: []analysis.RelatedInformation{
+			{
+				Pos:     owner.Pos(),
+				Message: fmt.Sprintf("owner type %s declared here", owner.Name()),
+			},
+		},
+	})
+}
+
+// ownerReleases returns true if one of the owner methods of owner makes
+// the expected call of rule on field, false otherwise.
+func (a *analyzer) ownerReleases(rule Rule, owner *types.Named, field string) bool {
+	owner = owner.Origin()
+	for i := 0; i < owner.NumMethods(); i++ {
+		if a.facts.owns(owner.Method(i)).released(rule.id, field) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// flow returns a flow to check rule for ident using the control-flow
+// graph of the innermost function in stack, or nil if there is none.
+// Returning ident from the function transfers the obligation to its
+// caller.
+func (a *analyzer) flow(rule Rule, ident *ast.Ident, stack []ast.Node) *flow {
+	for i := len(stack) - 1; i >= 0; i-- {
+		var f *flow
+		var typ *ast.FuncType
+		switch fn := stack[i].(type) {
+		case *ast.FuncLit:
+			f = newFlow(a.pass, a.log, a.facts, rule, ident, fn.Body, a.cfgs.FuncLit(fn))
+			typ = fn.Type
+		case *ast.FuncDecl:
+			g := a.cfgs.FuncDecl(fn)
+			if g == nil {
+				return nil
+			}
+			f = newFlow(a.pass, a.log, a.facts, rule, ident, fn.Body, g)
+			typ = fn.Type
+		default:
 			continue
 		}
-		a.report(ident, rule, ident.Name)
+
+		f.visitor.transfers(typ)
+
+		return f
 	}
+
+	return nil
 }
 
-// report reports a missing call for rule at rng for variable name.
-func (a *analyzer) report(rng analysis.Range, rule Rule, name string) {
+// report reports a missing call for rule at rng for variable name
+// with the suggested fixes.
+// If exits is not empty the first is named in the message and all
+// are included as related information.
+func (a *analyzer) report(rng analysis.Range, rule Rule, name string, fixes []analysis.SuggestedFix, exits ...exit) {
 	name = rule.name(name)
 	a.log.Debug().
 		Str("rule", rule.Name).
 		Str("name", name).
+		Int("exits", len(exits)).
 		Msg("not called")
-	a.pass.Report(analysis.Diagnostic{
-		Pos:      rng.Pos(),
-		End:      rng.End(),
-		Category: rule.Category,
-		Message:  fmt.Sprintf("%s must be called", name),
+
+	msg := fmt.Sprintf("%s must be called", name)
+	related := make([]analysis.RelatedInformation, len(exits))
+	for i, e := range exits {
+		related[i] = analysis.RelatedInformation{
+			Pos:     e.Pos(),
+			Message: fmt.Sprintf("%s not called before %s", name, e.kind),
+		}
+	}
+
+	if len(exits) > 0 {
+		msg = fmt.Sprintf("%s before %s at line %d",
+			msg,
+			exits[0].kind,
+			a.pass.Fset.Position(exits[0].Pos()).Line,
+		)
+	}
+
+	a.emit(rule, analysis.Diagnostic{
+		Pos:            rng.Pos(),
+		End:            rng.End(),
+		Category:       rule.Category,
+		Message:        msg,
+		Related:        related,
+		SuggestedFixes: fixes,
 	})
 }
+
+// reportMisses reports calls of rule which didn't count.
+func (a *analyzer) reportMisses(rule Rule, misses []*miss) {
+	for _, m := range misses {
+		call := types.ExprString(m.call)
+		category, msg := rule.Category, fmt.Sprintf("%s called before iteration finished", call)
+		if m.ignored {
+			category, msg = resultCategory, fmt.Sprintf("%s called but result ignored", call)
+		}
+
+		a.log.Debug().
+			Str("rule", rule.Name).
+			Str("call", call).
+			Bool("ignored", m.ignored).
+			Msg("call missed")
+		a.emit(rule, analysis.Diagnostic{
+			Pos:      m.call.Pos(),
+			End:      m.call.End(),
+			Category: category,
+			Message:  msg,
+		})
+	}
+}
+
+// emit reports diag for rule unless it's suppressed by a directive
+// or the baseline.
+func (a *analyzer) emit(rule Rule, diag analysis.Diagnostic) {
+	if a.suppressed(rule.Name, diag.Pos) {
+		a.log.Debug().
+			Str("rule", rule.Name).
+			Msg("suppressed")
+		return
+	}
+
+	if a.baseline == nil && a.record == nil {
+		a.publish(rule.Name, diag)
+		return
+	}
+
+	f := a.finding(rule, diag)
+	if a.record != nil && a.root {
+		a.found = append(a.found, f)
+	}
+
+	if a.baseline != nil && a.baseline.known(f) {
+		a.log.Debug().
+			Str("rule", rule.Name).
+			Str("fingerprint", f.Fingerprint).
+			Msg("baseline")
+		return
+	}
+
+	a.publish(rule.Name, diag)
+}
+
+// publish reports diag for the rule with id, adding it to the SARIF
+// report if configured.
+func (a *analyzer) publish(id string, diag analysis.Diagnostic) {
+	if a.sarif != nil && a.root {
+		a.results = append(a.results, a.sarifResult(id, diag))
+	}
+
+	a.pass.Report(diag)
+}
@@ -1,8 +1,14 @@
 package uncalled
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/analysistest"
 )
 
@@ -16,6 +22,240 @@ func Test(t *testing.T) {
 		),
 		"./context",
 		"./database/sql/rows/err",
+		"./database/sql/rows/wrapper",
+		"./database/sql/tx",
 		"./net/http/request/body/close",
 	)
 }
+
+func TestMethods(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(
+		t,
+		testdata,
+		NewAnalyzer(
+			testWriter(t),
+			ConfigFile(filepath.Join(testdata, "methods", "config.yaml")),
+		),
+		"./methods",
+	)
+}
+
+func TestArgs(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(
+		t,
+		testdata,
+		NewAnalyzer(
+			testWriter(t),
+			ConfigFile(filepath.Join(testdata, "args", "config.yaml")),
+		),
+		"./args",
+	)
+}
+
+func TestAll(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(
+		t,
+		testdata,
+		NewAnalyzer(
+			testWriter(t),
+			ConfigFile(filepath.Join(testdata, "all", "config.yaml")),
+		),
+		"./all",
+	)
+}
+
+func TestCheckResult(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(
+		t,
+		testdata,
+		NewAnalyzer(
+			testWriter(t),
+			ConfigFile(filepath.Join(testdata, "result", "config.yaml")),
+		),
+		"./result",
+	)
+}
+
+func TestStructural(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(
+		t,
+		testdata,
+		NewAnalyzer(
+			testWriter(t),
+			ConfigFile(filepath.Join(testdata, "structural", "config.yaml")),
+		),
+		"./structural",
+	)
+}
+
+func TestTypes(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(
+		t,
+		testdata,
+		NewAnalyzer(
+			testWriter(t),
+			ConfigFile(filepath.Join(testdata, "types", "config.yaml")),
+		),
+		"typetest/alias",
+		"typetest/generic",
+		"typetest/major",
+		"typetest/vendored",
+	)
+}
+
+func TestSuggestedFixes(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(
+		t,
+		testdata,
+		NewAnalyzer(
+			testWriter(t),
+		),
+		"./fix/defer",
+		"./fix/check",
+	)
+}
+
+func TestIgnore(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(
+		t,
+		testdata,
+		NewAnalyzer(
+			testWriter(t),
+			UnusedIgnores(true),
+		),
+		"./ignore",
+	)
+}
+
+func TestBaseline(t *testing.T) {
+	testdata := analysistest.TestData()
+	file := filepath.Join(t.TempDir(), "baseline.json")
+	a := NewAnalyzer(
+		testWriter(t),
+		Baseline(filepath.Join(testdata, "src", "baseline", "baseline.json")),
+		WriteBaseline(file),
+	)
+	analysistest.Run(t, testdata, a, "baseline")
+	finish(t, a)
+
+	// All findings are recorded, including those suppressed, but not
+	// those of dependencies.
+	b, err := loadBaseline(file)
+	require.NoError(t, err)
+
+	found := make(map[string]int)
+	for f, count := range b.findings {
+		require.Equal(t, "baseline", f.Package)
+		require.Equal(t, "sql-rows-err", f.Rule)
+		found[f.Function] += count
+	}
+
+	require.Equal(t, map[string]int{"Known": 1, "KnownOnce": 2, "New": 1}, found)
+}
+
+func TestWriteBaselineSameFile(t *testing.T) {
+	testdata := analysistest.TestData()
+	data, err := os.ReadFile(filepath.Join(testdata, "src", "baseline", "baseline.json"))
+	require.NoError(t, err)
+
+	// Updating a baseline in place must read it before it's rewritten.
+	file := filepath.Join(t.TempDir(), "baseline.json")
+	require.NoError(t, os.WriteFile(file, data, 0o600))
+	a := NewAnalyzer(testWriter(t))
+	require.NoError(t, a.Flags.Set("write-baseline", file))
+	require.NoError(t, a.Flags.Set("baseline", file))
+	analysistest.Run(t, testdata, a, "baseline")
+	finish(t, a)
+
+	b, err := loadBaseline(file)
+	require.NoError(t, err)
+
+	var count int
+	for f, n := range b.findings {
+		if f.Package == "baseline" {
+			count += n
+		}
+	}
+	require.Equal(t, 4, count)
+}
+
+func TestSARIF(t *testing.T) {
+	testdata := analysistest.TestData()
+	file := filepath.Join(t.TempDir(), "report.sarif")
+	a := NewAnalyzer(testWriter(t))
+	require.NoError(t, a.Flags.Set("format", "sarif"))
+	require.NoError(t, a.Flags.Set("o", file))
+	analysistest.Run(t, testdata, a, "./fix/defer")
+	finish(t, a)
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(data, &log))
+	require.Equal(t, sarifVersion, log.Version)
+	require.Len(t, log.Runs, 1)
+
+	run := log.Runs[0]
+	cfg, err := loadDefaultConfig()
+	require.NoError(t, err)
+	rules := run.Tool.Driver.Rules
+	require.Len(t, rules, len(cfg.Rules)+1)
+	for i, rule := range cfg.Rules {
+		require.Equal(t, rule.Name, rules[i].ID)
+		require.NotNil(t, rules[i].Help, rule.Name)
+		require.NotEmpty(t, rules[i].Help.Markdown, rule.Name)
+	}
+
+	var lines []int
+	for _, res := range run.Results {
+		loc := res.Locations[0].PhysicalLocation
+		require.True(t, strings.HasSuffix(loc.ArtifactLocation.URI, "fix/defer/defer.go"), loc.ArtifactLocation.URI)
+
+		require.NotNil(t, res.RuleIndex)
+		require.Equal(t, rules[*res.RuleIndex].ID, res.RuleID)
+		require.Len(t, res.Fixes, 1)
+		require.Len(t, res.Fixes[0].ArtifactChanges, 1)
+		lines = append(lines, loc.Region.StartLine)
+	}
+	require.Equal(t, []int{11, 16, 25, 30, 39, 47}, lines)
+}
+
+func TestConfigOpt(t *testing.T) {
+	cfg, err := loadDefaultConfig()
+	require.NoError(t, err)
+
+	// The config is shared by concurrent passes, which must not modify it.
+	testdata := analysistest.TestData()
+	analysistest.Run(
+		t,
+		testdata,
+		NewAnalyzer(
+			testWriter(t),
+			ConfigOpt(cfg),
+		),
+		"./context",
+		"./database/sql/rows/err",
+		"./database/sql/rows/wrapper",
+		"./database/sql/tx",
+		"./net/http/request/body/close",
+	)
+}
+
+// finish writes the reports of a, as the command line driver does once
+// all packages have been analysed, which analysistest doesn't.
+func finish(t *testing.T, a *analysis.Analyzer) {
+	t.Helper()
+
+	l, ok := a.Flags.Lookup("config").Value.(*loader)
+	require.True(t, ok)
+	require.NoError(t, l.finish())
+}
@@ -0,0 +1,232 @@
+package uncalled
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// baselineVersion is the current version of the baseline file format.
+const baselineVersion = 1
+
+// finding identifies a reported issue independent of its line number.
+type finding struct {
+	// Rule is the name of the rule reported.
+	Rule string `json:"rule"`
+
+	// Package is the path of the package containing the issue.
+	Package string `json:"package"`
+
+	// Function is the name of the function containing the issue.
+	Function string `json:"function,omitempty"`
+
+	// Fingerprint is a hash of the normalised statement containing
+	// the issue.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// baselineEntry is a finding and the number of times it was reported.
+type baselineEntry struct {
+	finding
+	Count int `json:"count"`
+}
+
+// baselineFile is the on disk format of a baseline.
+type baselineFile struct {
+	Version  int             `json:"version"`
+	Findings []baselineEntry `json:"findings"`
+}
+
+// baseline is a set of findings which is safe for concurrent use.
+type baseline struct {
+	file     string
+	mtx      sync.Mutex
+	findings map[finding]int
+}
+
+// loadBaseline returns the baseline read from file.
+func loadBaseline(file string) (*baseline, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read baseline: %w", err)
+	}
+
+	var bf baselineFile
+	if err := json.Unmarshal(data, &bf); err != nil {
+		return nil, fmt.Errorf("parse baseline %q: %w", file, err)
+	}
+
+	if bf.Version != baselineVersion {
+		return nil, fmt.Errorf("baseline %q: unsupported version %d, expected %d", file, bf.Version, baselineVersion)
+	}
+
+	b := &baseline{file: file, findings: make(map[finding]int, len(bf.Findings))}
+	for _, e := range bf.Findings {
+		b.findings[e.finding] += e.Count
+	}
+
+	return b, nil
+}
+
+// createBaseline returns a new empty baseline which is written to file
+// once the run finishes, so file isn't truncated before the analysis
+// runs, for example while it's still being read as a baseline.
+func createBaseline(file string) *baseline {
+	return &baseline{file: file, findings: make(map[finding]int)}
+}
+
+// known returns true if f is present in the baseline, consuming one
+// occurrence of it, false otherwise.
+func (b *baseline) known(f finding) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.findings[f] == 0 {
+		return false
+	}
+	b.findings[f]--
+
+	return true
+}
+
+// add adds findings to the baseline.
+func (b *baseline) add(findings []finding) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for _, f := range findings {
+		b.findings[f]++
+	}
+}
+
+// write writes the baseline to its file, sorted so the output is stable.
+func (b *baseline) write() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	bf := baselineFile{
+		Version:  baselineVersion,
+		Findings: make([]baselineEntry, 0, len(b.findings)),
+	}
+	for f, count := range b.findings {
+		bf.Findings = append(bf.Findings, baselineEntry{finding: f, Count: count})
+	}
+
+	sort.Slice(bf.Findings, func(i, j int) bool {
+		a, b := bf.Findings[i], bf.Findings[j]
+		switch {
+		case a.Package != b.Package:
+			return a.Package < b.Package
+		case a.Function != b.Function:
+			return a.Function < b.Function
+		case a.Rule != b.Rule:
+			return a.Rule < b.Rule
+		default:
+			return a.Fingerprint < b.Fingerprint
+		}
+	})
+
+	data, err := json.MarshalIndent(bf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal baseline: %w", err)
+	}
+
+	if err := os.WriteFile(b.file, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write baseline: %w", err)
+	}
+
+	return nil
+}
+
+// finding returns the finding for diag reported for rule.
+func (a *analyzer) finding(rule Rule, diag analysis.Diagnostic) finding {
+	f := finding{
+		Rule:    rule.Name,
+		Package: a.pass.Pkg.Path(),
+	}
+
+	file := a.file(diag.Pos)
+	if file == nil {
+		return f
+	}
+
+	end := diag.End
+	if !end.IsValid() {
+		end = diag.Pos
+	}
+
+	path, _ := astutil.PathEnclosingInterval(file, diag.Pos, end)
+	var stmt ast.Node
+	for _, n := range path {
+		switch n := n.(type) {
+		case ast.Stmt:
+			if stmt == nil {
+				stmt = n
+			}
+		case *ast.FuncDecl:
+			f.Function = funcName(n)
+		}
+	}
+
+	if stmt == nil && len(path) > 0 {
+		stmt = path[0]
+	}
+
+	f.Fingerprint = a.fingerprint(stmt)
+
+	return f
+}
+
+// file returns the file of the current package containing pos,
+// nil if there is none.
+func (a *analyzer) file(pos token.Pos) *ast.File {
+	tf := a.pass.Fset.File(pos)
+	for _, file := range a.pass.Files {
+		if a.pass.Fset.File(file.Pos()) == tf {
+			return file
+		}
+	}
+
+	return nil
+}
+
+// fingerprint returns a hash of the source of node with whitespace
+// normalised, so it's not affected by formatting or position changes.
+func (a *analyzer) fingerprint(node ast.Node) string {
+	if node == nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, a.pass.Fset, node); err != nil {
+		a.log.Error().Err(err).Msg("print node")
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(buf.String()), " ")))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// funcName returns the name of decl, qualified by its receiver type
+// if it's a method.
+func funcName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+
+	return "(" + types.ExprString(decl.Recv.List[0].Type) + ")." + decl.Name.Name
+}
@@ -3,11 +3,16 @@ package uncalled
 import (
 	"bytes"
 	_ "embed"
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
 	"go/types"
 	"io"
 	"os"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -17,11 +22,36 @@ import (
 
 const (
 	anyType = "_"
+
+	// fixDefer is the fix type which inserts a deferred call.
+	fixDefer = "defer"
+
+	// fixCheck is the fix type which inserts a check of the error
+	// returned by the call.
+	fixCheck = "check"
+
+	// defaultHandler is the default Fix.Handler.
+	defaultHandler = "panic($err)"
+
+	// configVersion is the current version of the configuration schema.
+	configVersion = 1
+
+	// defaultConfigName is the name used to report errors in the
+	// embedded configuration.
+	defaultConfigName = ".uncalled.yaml"
 )
 
 var (
 	// reName is pattern which validates rule names.
 	reName = regexp.MustCompile("^[a-z0-9-]+$")
+
+	// reImportDir is pattern which matches the directories of an
+	// import path, for example example.com/ in example.com/pkg.Type.
+	reImportDir = regexp.MustCompile(`[\w.~-]+/`)
+
+	// reMajor is pattern which matches the major version element of
+	// an import path, for example v2 in example.com/mod/v2.
+	reMajor = regexp.MustCompile(`^v([2-9]|[1-9][0-9]+)$`)
 )
 
 //go:embed .uncalled.yaml
@@ -38,7 +68,7 @@ func quote(s string) string {
 // loadDefaultConfig loads the default embedded configuration.
 func loadDefaultConfig() (*Config, error) {
 	cfg := &Config{}
-	if err := cfg.load(bytes.NewBuffer(defaultConfig)); err != nil {
+	if err := cfg.load(defaultConfigName, bytes.NewBuffer(defaultConfig)); err != nil {
 		return nil, fmt.Errorf("decode config %s: %w", quote(string(defaultConfig)), err)
 	}
 
@@ -47,6 +77,14 @@ func loadDefaultConfig() (*Config, error) {
 
 // Config represents the configuration for uncalled Analyzer.
 type Config struct {
+	// Version is the version of the configuration schema.
+	// Default: current version.
+	Version int
+
+	// DefaultCategory is the category used to report failures for rules
+	// which don't specify one.
+	DefaultCategory string `mapstructure:"default-category" yaml:"default-category"`
+
 	// DisableAll disables all rules.
 	DisableAll bool `mapstructure:"disable-all" yaml:"disable-all"`
 
@@ -59,6 +97,11 @@ type Config struct {
 	// Rules are the rules to process, disabled rules will be skipped.
 	Rules []Rule
 
+	// OwnerMethods are the names of methods which release the fields of
+	// their struct type. Storing a value in a field is only accepted if
+	// one of these methods makes the expected call on that field.
+	OwnerMethods []string `mapstructure:"owner-methods" yaml:"owner-methods"`
+
 	// rules lists all rules and their index in Rules.
 	rules map[string]Rule
 
@@ -75,19 +118,110 @@ func (c *Config) loadFile(file string) error {
 	}
 	defer f.Close()
 
-	return c.load(f)
+	return c.load(file, f)
 }
 
-// load loads the analyzer config from r.
-func (c *Config) load(r io.Reader) error {
-	dec := yaml.NewDecoder(r)
-	if err := dec.Decode(c); err != nil {
-		return fmt.Errorf("decode config: %q: %w", "file", err)
+// load loads the analyzer config from r, using name to report errors.
+// Unknown keys are rejected.
+func (c *Config) load(name string, r io.Reader) error {
+	var node yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&node); err != nil {
+		return fmt.Errorf("decode config %q: %w", name, err)
+	}
+
+	if err := checkKeys(name, &node, reflect.TypeOf(c)); err != nil {
+		return fmt.Errorf("decode config: %w", err)
+	}
+
+	if err := node.Decode(c); err != nil {
+		return fmt.Errorf("decode config %q: %w", name, err)
+	}
+
+	switch {
+	case c.Version == 0:
+		c.Version = configVersion
+	case c.Version < 0:
+		return fmt.Errorf("config %q: invalid version %d", name, c.Version)
+	case c.Version > configVersion:
+		return fmt.Errorf("config %q: version %d is newer than the supported version %d, upgrade uncalled", name, c.Version, configVersion)
 	}
 
 	return c.validate()
 }
 
+// checkKeys returns an error identifying the position of the first
+// mapping key in node, or its children, which doesn't match a field
+// of t, nil otherwise.
+// The decoder's KnownFields reports the line but not the column of
+// unknown keys, so they are checked against the node tree instead.
+func checkKeys(name string, node *yaml.Node, t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			if err := checkKeys(name, n, t); err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		return checkKeys(name, node.Alias, t)
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice {
+			return nil // Type mismatch is reported by decode.
+		}
+
+		for _, n := range node.Content {
+			if err := checkKeys(name, n, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		if t.Kind() != reflect.Struct {
+			return nil // Type mismatch is reported by decode.
+		}
+
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, val := node.Content[i], node.Content[i+1]
+			ft, ok := fields[key.Value]
+			if !ok {
+				return fmt.Errorf("%s:%d:%d: unknown key %q in %s", name, key.Line, key.Column, key.Value, t.Name())
+			}
+
+			if err := checkKeys(name, val, ft); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// yamlFields returns the types of the fields of the struct t keyed by
+// their yaml name.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		key := strings.ToLower(f.Name)
+		if tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			key = tag
+		}
+		fields[key] = f.Type
+	}
+
+	return fields
+}
+
 // string returns a YAML string representation of c.
 // If an error occurs it is returned instead.
 func (c *Config) string() string {
@@ -117,7 +251,7 @@ func (c *Config) copy() (*Config, error) {
 	}
 
 	var cfg Config
-	if err := cfg.load(bytes.NewBuffer(data)); err != nil {
+	if err := cfg.load("copy", bytes.NewBuffer(data)); err != nil {
 		return nil, err
 	}
 
@@ -135,9 +269,15 @@ func (c *Config) merge(other *Config) error {
 		return nil
 	}
 
+	if other.DefaultCategory != "" {
+		c.DefaultCategory = other.DefaultCategory
+	}
 	c.DisableAll = other.DisableAll
 	c.Disabled = other.Disabled
 	c.Enabled = other.Enabled
+	if other.OwnerMethods != nil {
+		c.OwnerMethods = other.OwnerMethods
+	}
 
 	for _, otherRule := range other.Rules {
 		rule, ok := c.rules[otherRule.Name]
@@ -153,6 +293,17 @@ func (c *Config) merge(other *Config) error {
 	return c.validate()
 }
 
+// ownerMethod returns true if name is one of the owner methods, false otherwise.
+func (c *Config) ownerMethod(name string) bool {
+	for _, m := range c.OwnerMethods {
+		if m == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 // validate validates the configuration.
 func (c *Config) validate() error {
 	c.active = make(map[string]Rule)
@@ -165,7 +316,10 @@ func (c *Config) validate() error {
 		}
 
 		r.idx = i
-		if !c.DisableAll {
+		if r.Category == "" {
+			r.Category = c.DefaultCategory
+		}
+		if !c.DisableAll && !r.Disabled {
 			c.active[r.Name] = r
 		}
 		c.rules[r.Name] = r
@@ -199,7 +353,11 @@ type Rule struct {
 	// Name is the name of the rule.
 	Name string
 
+	// Disabled disables this rule, unless it's listed in Config.Enabled.
+	Disabled bool
+
 	// Category is the category used to report failures for this rule.
+	// Default: Config.DefaultCategory.
 	Category string
 
 	// Packages is the list of package imports which to be considered
@@ -208,29 +366,70 @@ type Rule struct {
 	// skipped. At least one package must be specified.
 	Packages []string
 
+	// Methods is the list of fully qualified functions and methods which
+	// trigger this rule, for example `(*database/sql.DB).Query` or
+	// `context.WithTimeout`. If specified only these trigger the rule,
+	// regardless of their result types, otherwise any function which
+	// returns Results does.
+	Methods []string
+
 	// Results represents the results the matched methods return.
 	Results []*Result
 
+	// Args represents the arguments the matched methods are passed, one
+	// of which may be expected instead of a result, for example the
+	// channel passed to os/signal.Notify, which must be passed to
+	// os/signal.Stop. Requires Methods.
+	Args []*Result
+
 	// idx represents the index at which this rule was in Config.Rules.
 	idx int
 
-	// expects references the result which specifies a Method.
+	// expects references the result or arg which specifies a Method.
 	expects *Result
 
-	// expected calls is a map of fully qualified calls we expect.
-	expectedCalls map[string]struct{}
+	// expectations are the expectations of expects which are checked
+	// and reported independently.
+	expectations []*Expect
+
+	// expect is the expectation being checked.
+	expect *Expect
+
+	// id identifies expect in facts.
+	id string
 
-	// expectedType is a map of fully qualified types to monitor.
-	expectedTypes map[string]struct{}
+	// methods is a set built from Methods.
+	methods map[string]struct{}
 }
 
 // name returns the expected string based on ident.
 func (r Rule) name(ident string) string {
 	if ident == "" {
-		ident = strings.TrimLeft(r.expects.Type, ".")
+		ident = r.expects.label()
+	}
+
+	calls := r.expect.calls()
+	names := make([]string, len(calls))
+	for i, e := range calls {
+		names[i] = e.name(ident)
+	}
+
+	return strings.Join(names, " or ")
+}
+
+// obligations returns a copy of r for each of its expectations, which
+// are checked and reported independently.
+func (r Rule) obligations() []Rule {
+	rules := make([]Rule, len(r.expectations))
+	for i, e := range r.expectations {
+		rules[i] = r
+		rules[i].expect = e
+		if len(r.expectations) > 1 {
+			rules[i].id = fmt.Sprintf("%s.%d", r.Name, i)
+		}
 	}
 
-	return fmt.Sprintf("%s%s(%s)", ident, r.expects.Expect.Call, strings.Join(r.expects.Expect.Args, ","))
+	return rules
 }
 
 // validate returns an error if r isn't valid, nil otherwise.
@@ -240,27 +439,75 @@ func (r *Rule) validate() error {
 		return fmt.Errorf("rule %q: contains non alpha numeric or uppercase characters", r.Name)
 	case len(r.Packages) == 0:
 		return fmt.Errorf("rule %q: no packages", r.Name)
-	case len(r.Results) == 0:
+	case len(r.Results) == 0 && len(r.Args) == 0:
 		return fmt.Errorf("rule %q: no call results", r.Name)
 	}
 
 	for i, res := range r.Results {
+		res.idx = i
 		if res.Expect != nil {
 			if r.expects != nil {
 				return fmt.Errorf("rule %q: multiple results expecting a method", r.Name)
 			}
-			res.idx = i
 			r.expects = res
 		}
 	}
 
+	for i, arg := range r.Args {
+		arg.idx, arg.arg = i, true
+		if arg.Expect != nil {
+			if r.expects != nil {
+				return fmt.Errorf("rule %q: multiple results or args expecting a method", r.Name)
+			}
+			r.expects = arg
+		}
+	}
+
 	if r.expects == nil {
 		return fmt.Errorf("rule %q: no result expecting a method", r.Name)
 	}
 
-	r.expectedCalls = make(map[string]struct{})
-	r.expectedTypes = make(map[string]struct{})
-	for _, res := range r.Results {
+	if r.expects.arg {
+		switch {
+		case len(r.Methods) == 0:
+			return fmt.Errorf("rule %q: arg idx %d is expected without methods", r.Name, r.expects.idx)
+		case r.expects.Expect.IfNil != nil:
+			return fmt.Errorf("rule %q: if-nil is not supported by an expected arg", r.Name)
+		}
+
+		for _, e := range r.expects.Expect.leaves() {
+			if e.Fix != nil {
+				return fmt.Errorf("rule %q: fix is not supported by an expected arg", r.Name)
+			}
+		}
+	}
+
+	if idx := r.expects.Expect.IfNil; idx != nil {
+		switch {
+		case *idx < 0 || *idx >= len(r.Results):
+			return fmt.Errorf("rule %q: if-nil result idx %d out of range", r.Name, *idx)
+		case *idx == r.expects.idx:
+			return fmt.Errorf("rule %q: if-nil result idx %d is the expected result", r.Name, *idx)
+		}
+	}
+
+	if err := r.expects.Expect.build(r, true); err != nil {
+		return fmt.Errorf("rule %q: %w", r.Name, err)
+	}
+
+	r.expectations = r.expects.Expect.obligations()
+	r.expect = r.expectations[0]
+	r.id = r.Name
+
+	r.methods = make(map[string]struct{}, len(r.Methods))
+	for _, m := range r.Methods {
+		if !strings.Contains(m, ".") {
+			return fmt.Errorf("rule %q: method %q not fully qualified", r.Name, m)
+		}
+		r.methods[m] = struct{}{}
+	}
+
+	for _, res := range r.values() {
 		if err := res.build(r); err != nil {
 			return err
 		}
@@ -269,6 +516,53 @@ func (r *Rule) validate() error {
 	return nil
 }
 
+// values returns the results and args of r.
+func (r *Rule) values() []*Result {
+	values := make([]*Result, 0, len(r.Results)+len(r.Args))
+	return append(append(values, r.Results...), r.Args...)
+}
+
+// resolve resolves the interfaces the results of r must implement from
+// pkg and the packages it imports, returning false if any aren't found.
+func (r *Rule) resolve(pkg *types.Package) bool {
+	for _, res := range r.values() {
+		if !res.resolve(pkg) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// triggeredBy returns true if a call to fn, which may be nil for dynamic
+// calls, with signature sig triggers this rule, false otherwise.
+func (r *Rule) triggeredBy(fn *types.Func, sig *types.Signature) bool {
+	if len(r.methods) == 0 {
+		return r.matchesResults(sig.Results())
+	}
+
+	if fn == nil {
+		return false
+	}
+
+	if _, ok := r.methods[fn.Origin().FullName()]; !ok {
+		return false
+	}
+
+	if r.expects.arg {
+		// Ensure the argument carrying the obligation exists.
+		return r.expects.idx < sig.Params().Len()
+	}
+
+	// Ensure the results referenced by the rule exist.
+	n := sig.Results().Len()
+	if idx := r.expects.Expect.IfNil; idx != nil && *idx >= n {
+		return false
+	}
+
+	return r.expects.idx < n
+}
+
 // matchesResults returns true if the res matches the Results of this rule,
 // false otherwise.
 func (r *Rule) matchesResults(res *types.Tuple) bool {
@@ -285,18 +579,43 @@ func (r *Rule) matchesResults(res *types.Tuple) bool {
 	return true
 }
 
-// matchesCall returns true if call and name match the expected call for
-// this rule, false otherwise.
-func (r *Rule) matchesCall(call *ast.CallExpr, name string) bool {
-	if len(call.Args) != len(r.expects.Expect.Args) {
-		return false
+// matchingCalls returns the calls which satisfy the expectation being
+// checked that call and name match.
+func (r *Rule) matchingCalls(call *ast.CallExpr, name string) []*Expect {
+	var calls []*Expect
+	for _, e := range r.expect.calls() {
+		if e.matchesCall(call, name) {
+			calls = append(calls, e)
+		}
 	}
 
-	if strings.HasPrefix(r.expects.Expect.Call, ".") {
-		return r.expects.Expect.Call[1:] == name
+	return calls
+}
+
+// matchingFuncs returns the function calls which satisfy the expectation
+// being checked that call of fn matches.
+func (r *Rule) matchingFuncs(call *ast.CallExpr, fn *types.Func) []*Expect {
+	var calls []*Expect
+	for _, e := range r.expect.calls() {
+		if e.matchesFunc(call, fn) {
+			calls = append(calls, e)
+		}
 	}
 
-	return r.expects.Expect.Call == name
+	return calls
+}
+
+// self returns true if m matches the value which carries the obligation
+// of r, false otherwise.
+func (r *Rule) self(m argMatcher) bool {
+	switch m.kind {
+	case argResult:
+		return !r.expects.arg && m.result == r.expects.idx
+	case argArg:
+		return r.expects.arg && m.arg == r.expects.idx
+	default:
+		return false
+	}
 }
 
 // Result is a result expected from a rule call.
@@ -311,6 +630,15 @@ type Result struct {
 	// the named TypeName.
 	Pointer bool
 
+	// Implements if set matches any type which implements the interface
+	// qualified by its package path, for example io.Closer, instead of
+	// Type.
+	Implements string
+
+	// Kind if set matches any type whose underlying type is the given
+	// type, for example func(), instead of Type.
+	Kind string
+
 	// Expect sets the expectation for the result.
 	// At least one Result in a rule must have a method specified.
 	// If not specified no check it performed.
@@ -318,6 +646,63 @@ type Result struct {
 
 	idx   int
 	match resultMatcher
+
+	// arg is true if this is an argument of the call, not a result.
+	arg bool
+
+	// kind is the normalised Kind.
+	kind string
+
+	// iface is the interface named by Implements, set by resolve.
+	iface *types.Interface
+
+	// names are the types named by Type, nil if it doesn't name a type.
+	names []typeName
+
+	// objs are the types matched for names, with aliases resolved for
+	// the current package by resolve.
+	objs map[typeName]struct{}
+}
+
+// typeName identifies a declared type by its package path, without any
+// vendor prefix, and name.
+type typeName struct {
+	path string
+	name string
+}
+
+// newTypeName returns the typeName of obj.
+func newTypeName(obj *types.TypeName) typeName {
+	if obj.Pkg() == nil {
+		return typeName{name: obj.Name()} // Universe.
+	}
+
+	return typeName{path: unvendor(obj.Pkg().Path()), name: obj.Name()}
+}
+
+// String implements fmt.Stringer.
+func (n typeName) String() string {
+	if n.path == "" {
+		return n.name
+	}
+
+	return n.path + "." + n.name
+}
+
+// resolve returns the name of the type n denotes from pkg, which differs
+// if it names an alias or a type of a major version of its module, or n
+// if not found.
+func (n typeName) resolve(pkg *types.Package) typeName {
+	obj := lookupType(pkg, n.String())
+	if obj == nil {
+		return n
+	}
+
+	if named, ok := unalias(obj.Type()).(*types.Named); ok {
+		return newTypeName(named.Origin().Obj())
+	}
+
+	return newTypeName(obj)
 }
 
 // resultMatcher is a function which returns true if t matches, false otherwise.
@@ -325,34 +710,58 @@ type resultMatcher func(t types.Type) bool
 
 // build builds the matcher for this result.
 func (r *Result) build(rule *Rule) error {
-	resultTypes := make(map[string]struct{}, len(rule.Packages))
-	for _, p := range rule.Packages {
-		name := r.name(p)
-		resultTypes[name] = struct{}{}
-		if r.Expect == nil {
-			continue // Not expecting a method on this result to be called.
+	var set int
+	for _, s := range []string{r.Type, r.Implements, r.Kind} {
+		if s != "" {
+			set++
 		}
+	}
 
-		// Expected result type.
-		if r.Type == anyType {
-			return fmt.Errorf("rule %q: result idx %d is expected and wildcard", rule.Name, r.idx)
+	if set != 1 {
+		return fmt.Errorf("rule %q: %s idx %d must specify one of type, implements or kind", rule.Name, r.what(), r.idx)
+	}
+
+	if r.Expect != nil && r.Type == anyType {
+		return fmt.Errorf("rule %q: %s idx %d is expected and wildcard", rule.Name, r.what(), r.idx)
+	}
+
+	if r.Kind != "" {
+		expr, err := parser.ParseExpr(r.Kind)
+		if err != nil {
+			return fmt.Errorf("rule %q: %s idx %d: kind %q: %w", rule.Name, r.what(), r.idx, r.Kind, err)
 		}
+		r.kind = types.ExprString(expr)
+	}
 
-		name += r.Expect.Call
-		rule.expectedCalls[name] = struct{}{}
+	resultTypes := make(map[string]struct{}, len(rule.Packages))
+	r.names = nil
+	for _, p := range rule.Packages {
+		resultTypes[r.name(p)] = struct{}{}
+		if n, ok := r.declared(p); ok {
+			r.names = append(r.names, n)
+		}
+	}
 
-		parts := strings.Split(name, ".")
-		name = strings.Join(parts[:len(parts)-1], ".")
-		rule.expectedTypes[name] = struct{}{}
+	r.objs = nil
+	if r.names != nil {
+		r.objs = make(map[typeName]struct{}, len(r.names))
+		for _, n := range r.names {
+			r.objs[n] = struct{}{}
+		}
 	}
 
 	r.match = func(t types.Type) bool {
-		if t == nil {
+		switch {
+		case t == nil:
 			return false
-		}
-
-		if r.Type == anyType {
+		case r.Type == anyType:
 			return true // Matches any type.
+		case r.Implements != "":
+			return r.iface != nil && types.Implements(t, r.iface)
+		case r.Kind != "":
+			return types.TypeString(t.Underlying(), nil) == r.kind
+		case r.objs != nil:
+			return r.matchesObject(t)
 		}
 
 		_, ok := resultTypes[t.String()]
@@ -362,6 +771,93 @@ func (r *Result) build(rule *Rule) error {
 	return nil
 }
 
+// matchesObject returns true if t, or what it points to if Pointer, is
+// one of the types in objs or an instantiation of one, false otherwise.
+func (r *Result) matchesObject(t types.Type) bool {
+	if r.Pointer {
+		ptr, ok := unalias(t).(*types.Pointer)
+		if !ok {
+			return false
+		}
+		t = ptr.Elem()
+	}
+
+	named, ok := unalias(t).(*types.Named)
+	if !ok {
+		return false
+	}
+
+	_, ok = r.objs[newTypeName(named.Origin().Obj())]
+	return ok
+}
+
+// declared returns the type named by Type for pkg and true if it names
+// a type, false otherwise.
+func (r Result) declared(pkg string) (typeName, bool) {
+	name := r.Type
+	if strings.HasPrefix(name, ".") {
+		name = pkg + name
+	}
+
+	i := strings.LastIndex(name, ".")
+	n := typeName{name: name[i+1:]}
+	if i >= 0 {
+		n.path = unvendor(name[:i])
+	}
+
+	if !token.IsIdentifier(n.name) || strings.ContainsAny(n.path, " *[]()") {
+		return typeName{}, false // Not a type name, for example []byte.
+	}
+
+	return n, true
+}
+
+// resolve resolves the types of r from pkg and the packages it imports,
+// returning false if the interface named by Implements isn't found.
+func (r *Result) resolve(pkg *types.Package) bool {
+	if r.names != nil {
+		r.objs = make(map[typeName]struct{}, len(r.names))
+		for _, n := range r.names {
+			r.objs[n.resolve(pkg)] = struct{}{}
+		}
+	}
+
+	if r.Implements == "" {
+		return true
+	}
+
+	r.iface = nil
+	obj := lookupType(pkg, r.Implements)
+	if obj == nil {
+		return false
+	}
+
+	r.iface, _ = obj.Type().Underlying().(*types.Interface)
+
+	return r.iface != nil
+}
+
+// what returns what r describes, an arg or result.
+func (r Result) what() string {
+	if r.arg {
+		return "arg"
+	}
+
+	return "result"
+}
+
+// label returns the name used for the result in messages.
+func (r Result) label() string {
+	switch {
+	case r.Implements != "":
+		return r.Implements[strings.LastIndex(r.Implements, ".")+1:]
+	case r.Kind != "":
+		return r.what()
+	default:
+		return strings.TrimLeft(r.Type, ".")
+	}
+}
+
 // name returns the fully qualified type name for given pkg.
 func (r Result) name(pkg string) string {
 	if r.Type == anyType {
@@ -384,10 +880,429 @@ func (r Result) name(pkg string) string {
 type Expect struct {
 	// Call is the call to expect on this result.
 	// Methods called on the result should start with a "."
-	// for example .Err
+	// for example .Err, otherwise it's a package qualified function
+	// which must be passed the result, for example os/signal.Stop.
 	Call string
 
-	// Args are the arguments passed to the method.
-	// Currently on the count matters.
+	// Args are the arguments passed to the method, each one of:
+	// _ - matches any argument.
+	// $N - matches the value of result N of the triggering call,
+	// for example $0 for the result itself.
+	// @N - matches the value of argument N of the triggering call.
+	// A Go literal, for example "name", 1, true or nil - matches an
+	// argument with that constant value.
+	// A type, for example context.Context - matches an argument of
+	// that type.
 	Args []string
+
+	// After is a method of the result which the call must follow, if
+	// the method may be called after it, the call doesn't count, for
+	// example .Next for .Err on *sql.Rows.
+	After string
+
+	// CheckResult if true requires the result of the call, typically an
+	// error, to be used, for example compared, returned or passed to
+	// another call, for the call to count.
+	CheckResult bool `mapstructure:"check-result" yaml:"check-result"`
+
+	// IfNil is the index of a result, typically an error, which must
+	// be nil for the call to be expected. Paths on which the result is
+	// known to be non nil, for example those guarded by
+	// if err != nil { return }, are not required to make the call.
+	// If not specified the call is always expected.
+	IfNil *int `mapstructure:"if-nil" yaml:"if-nil"`
+
+	// Fix configures the suggested fix for a missing call.
+	// If not specified no fix is suggested.
+	Fix *Fix
+
+	// All are expectations which must all be met, each is checked and
+	// reported independently. If specified Call, Args and Fix must not
+	// be, for example Close and Err for *sql.Rows.
+	All []*Expect
+
+	// Any are calls of which any one must be made, they are checked and
+	// reported together. If specified Call, Args and Fix must not be, the
+	// fix of the first call which specifies one is suggested, for example
+	// Commit or Rollback for *sql.Tx.
+	Any []*Expect
+
+	// args are the matchers built from Args.
+	args []argMatcher
+}
+
+// obligations returns the expectations of e which are checked and
+// reported independently.
+func (e *Expect) obligations() []*Expect {
+	if len(e.All) == 0 {
+		return []*Expect{e}
+	}
+
+	var obs []*Expect
+	for _, c := range e.All {
+		obs = append(obs, c.obligations()...)
+	}
+
+	return obs
+}
+
+// calls returns the calls which satisfy the obligation e.
+func (e *Expect) calls() []*Expect {
+	if len(e.Any) != 0 {
+		return e.Any
+	}
+
+	return []*Expect{e}
+}
+
+// fixer returns the call of the obligation e which is used to suggest
+// fixes, nil if there is none.
+func (e *Expect) fixer() *Expect {
+	for _, c := range e.calls() {
+		if c.Fix != nil {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// leaves returns all the calls e and the expectations it contains expect.
+func (e *Expect) leaves() []*Expect {
+	if len(e.All) == 0 {
+		return e.calls()
+	}
+
+	var calls []*Expect
+	for _, c := range e.All {
+		calls = append(calls, c.leaves()...)
+	}
+
+	return calls
+}
+
+// function returns true if the call of e is a function, not a call on
+// the result.
+func (e *Expect) function() bool {
+	return e.Call != "" && !strings.HasPrefix(e.Call, ".")
+}
+
+// name returns the call of e on ident.
+func (e *Expect) name(ident string) string {
+	if e.function() {
+		// Qualified by package name not path.
+		return fmt.Sprintf("%s(%s)", e.Call[strings.LastIndex(e.Call, "/")+1:], strings.Join(e.Args, ","))
+	}
+
+	return fmt.Sprintf("%s%s(%s)", ident, e.Call, strings.Join(e.Args, ","))
+}
+
+// matchesCall returns true if call and name match the call of e,
+// false otherwise.
+func (e *Expect) matchesCall(call *ast.CallExpr, name string) bool {
+	if len(call.Args) != len(e.Args) || e.function() {
+		return false
+	}
+
+	if strings.HasPrefix(e.Call, ".") {
+		return e.Call[1:] == name
+	}
+
+	return e.Call == name
+}
+
+// matchesFunc returns true if call of fn matches the function call of
+// e, false otherwise.
+func (e *Expect) matchesFunc(call *ast.CallExpr, fn *types.Func) bool {
+	return e.function() && len(call.Args) == len(e.Args) && e.Call == fn.Origin().FullName()
+}
+
+// argKind is the kind of an argMatcher.
+type argKind int
+
+const (
+	// argAny matches any argument.
+	argAny argKind = iota
+
+	// argResult matches the value of a result of the triggering call.
+	argResult
+
+	// argArg matches the value of an argument of the triggering call.
+	argArg
+
+	// argValue matches a constant value.
+	argValue
+
+	// argNil matches the predeclared nil.
+	argNil
+
+	// argType matches an argument type.
+	argType
+)
+
+// argMatcher matches an argument of an expected call.
+type argMatcher struct {
+	kind argKind
+
+	// result is the index of the result for argResult.
+	result int
+
+	// arg is the index of the argument for argArg.
+	arg int
+
+	// value is the value for argValue.
+	value constant.Value
+
+	// typ is the type name for argType.
+	typ string
+
+	// src is the source of the argument for argValue.
+	src string
+}
+
+// leaf returns true if e specifies details of a call, false otherwise.
+func (e *Expect) leaf() bool {
+	return e.Call != "" || len(e.Args) != 0 || e.After != "" || e.CheckResult || e.Fix != nil
+}
+
+// build validates and builds e, and the expectations it contains, for
+// a call which triggers rule. top is true if e isn't contained by
+// another expectation.
+func (e *Expect) build(rule *Rule, top bool) error {
+	if !top && e.IfNil != nil {
+		return errors.New("if-nil is only supported by the top level expect")
+	}
+
+	if len(e.All) != 0 && len(e.Any) != 0 {
+		return errors.New("expect can't specify both all and any")
+	}
+
+	if len(e.All) != 0 {
+		if e.leaf() {
+			return errors.New("expect with all can't specify call, args, after, check-result or fix")
+		}
+
+		for i, c := range e.All {
+			if err := c.build(rule, false); err != nil {
+				return fmt.Errorf("all %d: %w", i, err)
+			}
+		}
+
+		return nil
+	}
+
+	if len(e.Any) != 0 {
+		if e.leaf() {
+			return errors.New("expect with any can't specify call, args, after, check-result or fix")
+		}
+
+		for i, c := range e.Any {
+			if len(c.All) != 0 || len(c.Any) != 0 {
+				return fmt.Errorf("any %d: must be a call", i)
+			}
+
+			if err := c.build(rule, false); err != nil {
+				return fmt.Errorf("any %d: %w", i, err)
+			}
+		}
+
+		return nil
+	}
+
+	if e.Fix != nil {
+		if err := e.Fix.validate(); err != nil {
+			return err
+		}
+	}
+
+	if e.After != "" && (!strings.HasPrefix(e.After, ".") || len(e.After) == 1) {
+		return fmt.Errorf("after %q must be a method starting with a \".\"", e.After)
+	}
+
+	if e.function() {
+		switch {
+		case strings.ContainsAny(e.Call, "()*") || strings.LastIndex(e.Call, ".") <= strings.LastIndex(e.Call, "/"):
+			return fmt.Errorf("call %q must be a method starting with a \".\" or a package qualified function", e.Call)
+		case e.Fix != nil:
+			return fmt.Errorf("call %q: fix is not supported by a function", e.Call)
+		}
+	}
+
+	var self bool
+	e.args = make([]argMatcher, len(e.Args))
+	for i, arg := range e.Args {
+		m, err := parseArg(arg)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case m.kind == argResult && m.result >= len(rule.Results):
+			return fmt.Errorf("arg %d: result idx %d out of range", i, m.result)
+		case m.kind == argArg && len(rule.Args) != 0 && m.arg >= len(rule.Args):
+			return fmt.Errorf("arg %d: arg idx %d out of range", i, m.arg)
+		}
+		self = self || rule.self(m)
+		e.args[i] = m
+	}
+
+	if e.function() && !self {
+		return fmt.Errorf("call %q must be passed the expected %s", e.Call, rule.expects.what())
+	}
+
+	return nil
+}
+
+// parseArg returns the matcher for arg.
+func parseArg(arg string) (argMatcher, error) {
+	arg = strings.TrimSpace(arg)
+	switch {
+	case arg == "":
+		return argMatcher{}, errors.New("empty arg")
+	case arg == anyType:
+		return argMatcher{kind: argAny}, nil
+	case strings.HasPrefix(arg, "$"):
+		idx, err := strconv.Atoi(arg[1:])
+		if err != nil || idx < 0 {
+			return argMatcher{}, fmt.Errorf("arg %q: invalid result reference", arg)
+		}
+		return argMatcher{kind: argResult, result: idx}, nil
+	case strings.HasPrefix(arg, "@"):
+		idx, err := strconv.Atoi(arg[1:])
+		if err != nil || idx < 0 {
+			return argMatcher{}, fmt.Errorf("arg %q: invalid arg reference", arg)
+		}
+		return argMatcher{kind: argArg, arg: idx}, nil
+	}
+
+	expr, err := parser.ParseExpr(arg)
+	if err != nil {
+		if qualifiedType(arg) {
+			return argMatcher{kind: argType, typ: arg}, nil
+		}
+		return argMatcher{}, fmt.Errorf("arg %q: %w", arg, err)
+	}
+
+	neg := false
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.SUB {
+		neg = true
+		expr = u.X
+	}
+
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		v := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		if neg {
+			v = constant.UnaryOp(token.SUB, v, 0)
+		}
+		return argMatcher{kind: argValue, value: v, src: arg}, nil
+	case *ast.Ident:
+		switch {
+		case neg:
+			return argMatcher{}, fmt.Errorf("arg %q: invalid literal", arg)
+		case e.Name == "true" || e.Name == "false":
+			return argMatcher{kind: argValue, value: constant.MakeBool(e.Name == "true"), src: arg}, nil
+		case e.Name == "nil":
+			return argMatcher{kind: argNil}, nil
+		}
+	}
+
+	if neg || !typeExpr(expr) && !qualifiedType(arg) {
+		return argMatcher{}, fmt.Errorf("arg %q: not a literal or type", arg)
+	}
+
+	return argMatcher{kind: argType, typ: arg}, nil
+}
+
+// qualifiedType returns true if arg is a type qualified by an import
+// path, for example *example.com/pkg.Type, false otherwise.
+func qualifiedType(arg string) bool {
+	// Import paths aren't valid Go, so parse only their last element.
+	expr, err := parser.ParseExpr(reImportDir.ReplaceAllString(arg, ""))
+	return err == nil && typeExpr(expr)
+}
+
+// typeExpr returns true if expr is a type, false otherwise.
+func typeExpr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		_, ok := e.X.(*ast.Ident)
+		return ok
+	case *ast.StarExpr:
+		return typeExpr(e.X)
+	case *ast.ArrayType:
+		return typeExpr(e.Elt)
+	case *ast.MapType:
+		return typeExpr(e.Key) && typeExpr(e.Value)
+	case *ast.ChanType:
+		return typeExpr(e.Value)
+	case *ast.FuncType, *ast.InterfaceType, *ast.StructType:
+		return true
+	default:
+		return false
+	}
+}
+
+// source returns the Go source of the argument m matches, and true if
+// m matches a single value, false otherwise. Results are named by refs
+// and the arguments of the triggering call by args.
+func (m argMatcher) source(refs, args []string) (string, bool) {
+	switch m.kind {
+	case argResult:
+		if m.result >= len(refs) || refs[m.result] == "" || refs[m.result] == "_" {
+			return "", false
+		}
+		return refs[m.result], true
+	case argArg:
+		if m.arg >= len(args) || args[m.arg] == "" {
+			return "", false
+		}
+		return args[m.arg], true
+	case argValue:
+		return m.src, true
+	case argNil:
+		return "nil", true
+	default:
+		return "", false
+	}
+}
+
+// Fix represents the suggested fix for a missing call.
+type Fix struct {
+	// Type is the type of fix, one of:
+	// defer - inserts a deferred call after the value is obtained,
+	// or after the if-nil guard which follows it.
+	// check - inserts a check of the error returned by the call after
+	// the last use of the After method on the value, or without After
+	// the last loop over it, returning the error if the function
+	// returns an error and calling Handler otherwise.
+	Type string
+
+	// Handler is the statement used by check to handle the error if the
+	// function doesn't return an error, $err is replaced by the error.
+	// Any package it uses must already be imported.
+	// Default: panic($err).
+	Handler string
+}
+
+// handler returns the handler statement for err.
+func (f *Fix) handler(err string) string {
+	h := f.Handler
+	if h == "" {
+		h = defaultHandler
+	}
+
+	return strings.ReplaceAll(h, "$err", err)
+}
+
+// validate returns an error if f isn't valid, nil otherwise.
+func (f *Fix) validate() error {
+	switch f.Type {
+	case fixDefer, fixCheck:
+		return nil
+	default:
+		return fmt.Errorf("unknown fix type %q", f.Type)
+	}
 }
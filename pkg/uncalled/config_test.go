@@ -2,6 +2,8 @@ package uncalled
 
 import (
 	_ "embed"
+	"go/constant"
+	"strings"
 	"sync"
 	"testing"
 
@@ -133,6 +135,397 @@ func TestConfig_validate(t *testing.T) {
 			},
 			err: `rule "my-rule": result idx 0 is expected and wildcard`,
 		},
+		"if-nil-out-of-range": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"database/sql"},
+						Results: []*Result{
+							{
+								Type:   ".Rows",
+								Expect: &Expect{Call: ".Err", IfNil: intPtr(2)},
+							},
+							{
+								Type: "error",
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": if-nil result idx 2 out of range`,
+		},
+		"if-nil-expected": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"database/sql"},
+						Results: []*Result{
+							{
+								Type:   ".Rows",
+								Expect: &Expect{Call: ".Err", IfNil: intPtr(0)},
+							},
+							{
+								Type: "error",
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": if-nil result idx 0 is the expected result`,
+		},
+		"unknown-fix": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"context"},
+						Results: []*Result{
+							{
+								Type:   ".CancelFunc",
+								Expect: &Expect{Fix: &Fix{Type: "other"}},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": unknown fix type "other"`,
+		},
+		"unqualified-method": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"context"},
+						Methods:  []string{"WithCancel"},
+						Results: []*Result{
+							{
+								Type:   ".CancelFunc",
+								Expect: &Expect{},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": method "WithCancel" not fully qualified`,
+		},
+		"arg-result-out-of-range": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"context"},
+						Results: []*Result{
+							{
+								Type:   ".CancelFunc",
+								Expect: &Expect{Args: []string{"$1"}},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": arg 0: result idx 1 out of range`,
+		},
+		"arg-result-invalid": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"context"},
+						Results: []*Result{
+							{
+								Type:   ".CancelFunc",
+								Expect: &Expect{Args: []string{"$x"}},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": arg "$x": invalid result reference`,
+		},
+		"arg-arg-out-of-range": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"context"},
+						Results: []*Result{
+							{
+								Type:   ".CancelFunc",
+								Expect: &Expect{Args: []string{"@1"}},
+							},
+						},
+						Args: []*Result{
+							{
+								Type: ".Context",
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": arg 0: arg idx 1 out of range`,
+		},
+		"arg-invalid": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"context"},
+						Results: []*Result{
+							{
+								Type:   ".CancelFunc",
+								Expect: &Expect{Args: []string{"context.Context("}},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": arg "context.Context(": 1:17: expected ')', found 'EOF'`,
+		},
+		"expected-arg-no-methods": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"os/signal"},
+						Args: []*Result{
+							{
+								Kind:   "chan os.Signal",
+								Expect: &Expect{Call: "os/signal.Stop", Args: []string{"@0"}},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": arg idx 0 is expected without methods`,
+		},
+		"expected-arg-fix": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"os/signal"},
+						Methods:  []string{"os/signal.Notify"},
+						Args: []*Result{
+							{
+								Kind: "chan os.Signal",
+								Expect: &Expect{
+									Call: ".Close",
+									Fix:  &Fix{Type: fixDefer},
+								},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": fix is not supported by an expected arg`,
+		},
+		"function-not-passed": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"os/signal"},
+						Methods:  []string{"os/signal.Notify"},
+						Args: []*Result{
+							{
+								Kind:   "chan os.Signal",
+								Expect: &Expect{Call: "os/signal.Stop", Args: []string{"_"}},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": call "os/signal.Stop" must be passed the expected arg`,
+		},
+		"function-unqualified": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"os/signal"},
+						Methods:  []string{"os/signal.Notify"},
+						Args: []*Result{
+							{
+								Kind:   "chan os.Signal",
+								Expect: &Expect{Call: "Stop", Args: []string{"@0"}},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": call "Stop" must be a method starting with a "." or a package qualified function`,
+		},
+		"after-not-method": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"database/sql"},
+						Results: []*Result{
+							{
+								Type:   ".Rows",
+								Expect: &Expect{Call: ".Err", After: "Next"},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": after "Next" must be a method starting with a "."`,
+		},
+		"result-type-and-implements": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"os"},
+						Results: []*Result{
+							{
+								Type:       ".File",
+								Implements: "io.Closer",
+								Expect:     &Expect{Call: ".Close"},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": result idx 0 must specify one of type, implements or kind`,
+		},
+		"result-no-type": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"os"},
+						Results: []*Result{
+							{
+								Implements: "io.Closer",
+								Expect:     &Expect{Call: ".Close"},
+							},
+							{},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": result idx 1 must specify one of type, implements or kind`,
+		},
+		"result-bad-kind": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"context"},
+						Results: []*Result{
+							{
+								Kind:   "func(",
+								Expect: &Expect{},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": result idx 0: kind "func(": 1:6: expected ')', found 'EOF'`,
+		},
+		"all-with-call": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"database/sql"},
+						Results: []*Result{
+							{
+								Type: ".Rows",
+								Expect: &Expect{
+									Call: ".Close",
+									All:  []*Expect{{Call: ".Err"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": expect with all can't specify call, args, after, check-result or fix`,
+		},
+		"all-if-nil": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"database/sql"},
+						Results: []*Result{
+							{
+								Type: ".Rows",
+								Expect: &Expect{
+									All: []*Expect{{Call: ".Err", IfNil: intPtr(1)}},
+								},
+							},
+							{Type: "error"},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": all 0: if-nil is only supported by the top level expect`,
+		},
+		"any-with-call": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"database/sql"},
+						Results: []*Result{
+							{
+								Type: ".Tx",
+								Expect: &Expect{
+									Call: ".Commit",
+									Any:  []*Expect{{Call: ".Rollback"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": expect with any can't specify call, args, after, check-result or fix`,
+		},
+		"any-nested": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"database/sql"},
+						Results: []*Result{
+							{
+								Type: ".Tx",
+								Expect: &Expect{
+									Any: []*Expect{{All: []*Expect{{Call: ".Commit"}}}},
+								},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": any 0: must be a call`,
+		},
+		"all-and-any": {
+			cfg: Config{
+				Rules: []Rule{
+					{
+						Name:     "my-rule",
+						Packages: []string{"database/sql"},
+						Results: []*Result{
+							{
+								Type: ".Tx",
+								Expect: &Expect{
+									All: []*Expect{{Call: ".Commit"}},
+									Any: []*Expect{{Call: ".Rollback"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			err: `rule "my-rule": expect can't specify both all and any`,
+		},
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -147,6 +540,238 @@ func TestConfig_validate(t *testing.T) {
 	}
 }
 
+func TestConfig_load(t *testing.T) {
+	const rule = `
+rules:
+  - name: my-rule
+    packages:
+      - context
+    results:
+      - type: .Context
+      - type: .CancelFunc
+        expect:
+          call:
+`
+	tests := map[string]struct {
+		yaml     string
+		err      string
+		category string
+		active   bool
+	}{
+		"default-category": {
+			yaml:     "default-category: my-category" + rule,
+			category: "my-category",
+			active:   true,
+		},
+		"category": {
+			yaml:     "default-category: my-category" + rule + "    category: other\n",
+			category: "other",
+			active:   true,
+		},
+		"disabled": {
+			yaml:   rule + "    disabled: true\n",
+			active: false,
+		},
+		"unknown-key": {
+			yaml: "pakages: []\n",
+			err:  `decode config: test.yaml:1:1: unknown key "pakages" in Config`,
+		},
+		"unknown-nested-key": {
+			yaml: rule + "    pakages: []\n",
+			err:  `decode config: test.yaml:11:5: unknown key "pakages" in Rule`,
+		},
+		"unknown-result-key": {
+			yaml: strings.Replace(rule, "type: .Context", "typ: .Context", 1),
+			err:  `decode config: test.yaml:7:9: unknown key "typ" in Result`,
+		},
+		"newer-version": {
+			yaml: "version: 2" + rule,
+			err:  `config "test.yaml": version 2 is newer than the supported version 1, upgrade uncalled`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var cfg Config
+			err := cfg.load("test.yaml", strings.NewReader(tt.yaml))
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, configVersion, cfg.Version)
+			require.Equal(t, tt.category, cfg.rules["my-rule"].Category)
+			_, active := cfg.active["my-rule"]
+			require.Equal(t, tt.active, active)
+		})
+	}
+}
+
+func Test_parseArg(t *testing.T) {
+	tests := map[string]struct {
+		arg  string
+		want argMatcher
+		err  string
+	}{
+		"any":       {arg: "_", want: argMatcher{kind: argAny}},
+		"result":    {arg: "$2", want: argMatcher{kind: argResult, result: 2}},
+		"arg":       {arg: "@1", want: argMatcher{kind: argArg, arg: 1}},
+		"string":    {arg: `"name"`, want: argMatcher{kind: argValue, value: constant.MakeString("name"), src: `"name"`}},
+		"int":       {arg: "-1", want: argMatcher{kind: argValue, value: constant.MakeInt64(-1), src: "-1"}},
+		"bool":      {arg: "true", want: argMatcher{kind: argValue, value: constant.MakeBool(true), src: "true"}},
+		"nil":       {arg: "nil", want: argMatcher{kind: argNil}},
+		"type":      {arg: "*database/sql.Rows", want: argMatcher{kind: argType, typ: "*database/sql.Rows"}},
+		"named":     {arg: "context.Context", want: argMatcher{kind: argType, typ: "context.Context"}},
+		"chan":      {arg: "chan os.Signal", want: argMatcher{kind: argType, typ: "chan os.Signal"}},
+		"empty":     {arg: " ", err: "empty arg"},
+		"bad-arg":   {arg: "@x", err: `arg "@x": invalid arg reference`},
+		"bad-ref":   {arg: "$-1", err: `arg "$-1": invalid result reference`},
+		"typo":      {arg: `"done`, err: `arg "\"done": 1:1: string literal not terminated`},
+		"call":      {arg: "ctx.Done()", err: `arg "ctx.Done()": not a literal or type`},
+		"neg-ident": {arg: "-x", err: `arg "-x": invalid literal`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseArg(tt.arg)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// intPtr returns a pointer to i.
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestResult_declared(t *testing.T) {
+	tests := map[string]struct {
+		res    Result
+		want   typeName
+		wantOk bool
+	}{
+		"relative": {
+			res:    Result{Type: ".Rows"},
+			want:   typeName{path: "database/sql", name: "Rows"},
+			wantOk: true,
+		},
+		"qualified": {
+			res:    Result{Type: "context.Context"},
+			want:   typeName{path: "context", name: "Context"},
+			wantOk: true,
+		},
+		"universe": {
+			res:    Result{Type: "error"},
+			want:   typeName{name: "error"},
+			wantOk: true,
+		},
+		"slice": {
+			res: Result{Type: "[]byte"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := tt.res.declared("database/sql")
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_unvendor(t *testing.T) {
+	tests := map[string]string{
+		"database/sql":                         "database/sql",
+		"vendor/golang.org/x/net/http2/hpack":  "golang.org/x/net/http2/hpack",
+		"example.com/app/vendor/example.com/x": "example.com/x",
+		"example.com/vendored":                 "example.com/vendored",
+	}
+	for path, want := range tests {
+		t.Run(path, func(t *testing.T) {
+			require.Equal(t, want, unvendor(path))
+		})
+	}
+}
+
+func Test_matchesPath(t *testing.T) {
+	tests := map[string]struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		"equal": {
+			pattern: "database/sql",
+			path:    "database/sql",
+			want:    true,
+		},
+		"major": {
+			pattern: "example.com/major",
+			path:    "example.com/major/v2",
+			want:    true,
+		},
+		"major-exact": {
+			pattern: "example.com/major/v2",
+			path:    "example.com/major/v2",
+			want:    true,
+		},
+		"major-vendored": {
+			pattern: "example.com/x",
+			path:    "example.com/app/vendor/example.com/x/v3",
+			want:    true,
+		},
+		"major-distinct": {
+			pattern: "google.golang.org/api/drive/v2",
+			path:    "google.golang.org/api/drive/v3",
+		},
+		"major-not-trailing": {
+			pattern: "example.com/major/pkg",
+			path:    "example.com/major/v10/pkg",
+		},
+		"v1": {
+			pattern: "example.com/api",
+			path:    "example.com/api/v1",
+		},
+		"gopkg": {
+			pattern: "gopkg.in/yaml",
+			path:    "gopkg.in/yaml.v3",
+		},
+		"prefix": {
+			pattern: "example.com/major",
+			path:    "example.com/major/pkg",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.want, matchesPath(tt.pattern, tt.path))
+		})
+	}
+}
+
+func TestFix_handler(t *testing.T) {
+	tests := map[string]struct {
+		fix  Fix
+		want string
+	}{
+		"default": {
+			fix:  Fix{Type: fixCheck},
+			want: "panic(err)",
+		},
+		"log": {
+			fix:  Fix{Type: fixCheck, Handler: `log.Printf("rows: %v", $err)`},
+			want: `log.Printf("rows: %v", err)`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.fix.handler("err"))
+		})
+	}
+}
+
 type copyRes struct {
 	cfg *Config
 	err error
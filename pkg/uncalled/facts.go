@@ -0,0 +1,566 @@
+package uncalled
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// errorType is the predeclared error type.
+var errorType = types.Universe.Lookup("error").Type()
+
+// releasesFact is an analysis.Fact which records the parameters
+// of a function which always have a rules expected method called.
+type releasesFact struct {
+	// Params maps rule names to the indices of parameters released.
+	Params map[string][]int
+}
+
+// AFact implements analysis.Fact.
+func (*releasesFact) AFact() {}
+
+// String implements fmt.Stringer.
+func (f *releasesFact) String() string {
+	rules := make([]string, 0, len(f.Params))
+	for rule, params := range f.Params {
+		rules = append(rules, fmt.Sprintf("%s%v", rule, params))
+	}
+	sort.Strings(rules)
+
+	return "releases(" + strings.Join(rules, " ") + ")"
+}
+
+// released returns true if f records rule releasing parameter idx,
+// false otherwise.
+func (f *releasesFact) released(rule string, idx int) bool {
+	if f == nil {
+		return false
+	}
+
+	for _, i := range f.Params[rule] {
+		if i == idx {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ownsFact is an analysis.Fact which records the fields of the receiver
+// of an owner method which it always has a rules expected method called on.
+type ownsFact struct {
+	// Fields maps rule names to the names of the fields released.
+	Fields map[string][]string
+}
+
+// AFact implements analysis.Fact.
+func (*ownsFact) AFact() {}
+
+// String implements fmt.Stringer.
+func (f *ownsFact) String() string {
+	rules := make([]string, 0, len(f.Fields))
+	for rule, fields := range f.Fields {
+		rules = append(rules, fmt.Sprintf("%s%v", rule, fields))
+	}
+	sort.Strings(rules)
+
+	return "owns(" + strings.Join(rules, " ") + ")"
+}
+
+// released returns true if f records rule releasing field, false otherwise.
+func (f *ownsFact) released(rule, field string) bool {
+	if f == nil {
+		return false
+	}
+
+	for _, name := range f.Fields[rule] {
+		if name == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acquiresFact is an analysis.Fact which records the results of a
+// function which carry a rules obligation to its callers.
+type acquiresFact struct {
+	// Results maps rule names to the result acquired.
+	Results map[string]acquired
+}
+
+// AFact implements analysis.Fact.
+func (*acquiresFact) AFact() {}
+
+// String implements fmt.Stringer.
+func (f *acquiresFact) String() string {
+	rules := make([]string, 0, len(f.Results))
+	for rule, acq := range f.Results {
+		rules = append(rules, fmt.Sprintf("%s[%d]", rule, acq.Result))
+	}
+	sort.Strings(rules)
+
+	return "acquires(" + strings.Join(rules, " ") + ")"
+}
+
+// acquired represents a result which carries a rules obligation.
+type acquired struct {
+	// Result is the index of the result the call is expected on.
+	Result int
+
+	// IfNil is the index of the result which must be nil for the
+	// call to be expected, -1 if the call is always expected.
+	IfNil int
+}
+
+// facts computes, exports and imports releasesFact, acquiresFact
+// and ownsFact for functions.
+type facts struct {
+	pass  *analysis.Pass
+	cfgs  *ctrlflow.CFGs
+	log   zerolog.Logger
+	rules map[string]Rule
+
+	// owner returns true if the method name releases the fields of its
+	// receiver, false otherwise.
+	owner func(name string) bool
+
+	// decls maps functions in the current package to their declaration.
+	decls map[*types.Func]*ast.FuncDecl
+
+	// funcs lists the functions in decls in source order.
+	funcs []*types.Func
+
+	// cache contains the computed fact for each function in the current
+	// package, nil if the function releases nothing.
+	cache map[*types.Func]*releasesFact
+
+	// acquired contains the computed fact for each function in the
+	// current package, nil if the function acquires nothing.
+	acquired map[*types.Func]*acquiresFact
+
+	// owned contains the computed fact for each owner method in the
+	// current package, nil if the method releases no fields.
+	owned map[*types.Func]*ownsFact
+}
+
+// newFacts returns a new facts for the files in pass checking rules,
+// where owner reports if a method releases the fields of its receiver.
+func newFacts(pass *analysis.Pass, cfgs *ctrlflow.CFGs, log zerolog.Logger, rules map[string]Rule, owner func(name string) bool) *facts {
+	f := &facts{
+		pass:  pass,
+		cfgs:  cfgs,
+		log:   log,
+		rules: rules,
+		owner: owner,
+		decls: make(map[*types.Func]*ast.FuncDecl),
+		cache: make(map[*types.Func]*releasesFact),
+
+		acquired: make(map[*types.Func]*acquiresFact),
+		owned:    make(map[*types.Func]*ownsFact),
+	}
+
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue // Not a function or no body.
+			}
+
+			if fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+				f.decls[fn] = fd
+				f.funcs = append(f.funcs, fn)
+			}
+		}
+	}
+
+	return f
+}
+
+// export computes and exports the facts for all functions in the
+// current package.
+func (f *facts) export() {
+	for _, fn := range f.funcs {
+		f.releases(fn)
+		f.acquires(fn)
+		f.owns(fn)
+	}
+}
+
+// trigger returns the result of call which carries the obligation of
+// rule and true if call triggers rule, false otherwise.
+func (f *facts) trigger(rule Rule, call *ast.CallExpr) (acquired, bool) {
+	tv, ok := f.pass.TypesInfo.Types[call.Fun]
+	if !ok || tv.IsType() {
+		return acquired{}, false // Unknown or conversion.
+	}
+
+	callee, _ := typeutil.Callee(f.pass.TypesInfo, call).(*types.Func)
+	if sig, ok := tv.Type.(*types.Signature); ok && rule.triggeredBy(callee, sig) {
+		acq := acquired{Result: rule.expects.idx, IfNil: -1}
+		if idx := rule.expects.Expect.IfNil; idx != nil {
+			acq.IfNil = *idx
+		}
+		return acq, true
+	}
+
+	fn := typeutil.StaticCallee(f.pass.TypesInfo, call)
+	if fn == nil {
+		return acquired{}, false // Dynamic call or builtin.
+	}
+
+	fact := f.acquires(fn)
+	if fact == nil {
+		return acquired{}, false
+	}
+
+	acq, ok := fact.Results[rule.Name]
+	return acq, ok
+}
+
+// acquires returns the fact for fn, nil if it acquires nothing.
+func (f *facts) acquires(fn *types.Func) *acquiresFact {
+	if fn.Pkg() != f.pass.Pkg {
+		fact := &acquiresFact{}
+		if !f.pass.ImportObjectFact(fn, fact) {
+			return nil
+		}
+		return fact
+	}
+
+	if fact, ok := f.acquired[fn]; ok {
+		return fact
+	}
+
+	decl, ok := f.decls[fn]
+	if !ok {
+		return nil // No body.
+	}
+
+	// Break recursive cycles by assuming nothing is acquired.
+	f.acquired[fn] = nil
+
+	sig := fn.Type().(*types.Signature) //nolint: forcetypeassert
+	fact := &acquiresFact{Results: make(map[string]acquired)}
+	for _, rule := range f.rules {
+		if acq, ok := f.returned(rule, decl, sig); ok {
+			fact.Results[rule.Name] = acq
+		}
+	}
+
+	if len(fact.Results) == 0 {
+		return nil
+	}
+
+	f.log.Debug().
+		Stringer("func", fn).
+		Stringer("fact", fact).
+		Msg("export")
+	f.acquired[fn] = fact
+	f.pass.ExportObjectFact(fn, fact)
+
+	return fact
+}
+
+// returned returns the result of decl, with signature sig, which returns
+// a value carrying the obligation of rule and true if there is one, false
+// otherwise.
+func (f *facts) returned(rule Rule, decl *ast.FuncDecl, sig *types.Signature) (acquired, bool) {
+	if rule.expects.arg {
+		return acquired{}, false // Arguments aren't returned.
+	}
+
+	var named []*ast.Ident
+	if decl.Type.Results != nil {
+		for _, field := range decl.Type.Results.List {
+			named = append(named, field.Names...)
+		}
+	}
+
+	var acq acquired
+	var found bool
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return false // Returns are from the literal.
+		case *ast.ReturnStmt:
+			acq, found = f.returnedBy(rule, decl.Body, sig, n, named)
+		}
+		return !found
+	})
+
+	return acq, found
+}
+
+// returnedBy returns the result returned by ret which carries the
+// obligation of rule and true if there is one, false otherwise.
+// The result which must be nil for the call to be expected is the one
+// propagated from the acquisition if any, only if the acquisition has one.
+func (f *facts) returnedBy(rule Rule, body *ast.BlockStmt, sig *types.Signature, ret *ast.ReturnStmt, named []*ast.Ident) (acquired, bool) {
+	results := ret.Results
+	if len(results) == 0 {
+		// Bare return of named results.
+		for _, ident := range named {
+			results = append(results, ident)
+		}
+	}
+
+	if len(results) == 1 && len(named) != 1 {
+		// Tuple return: return f()
+		if call, ok := astutil.Unparen(results[0]).(*ast.CallExpr); ok {
+			if acq, ok := f.trigger(rule, call); ok {
+				return acq, true
+			}
+		}
+	}
+
+	for i, expr := range results {
+		switch e := astutil.Unparen(expr).(type) {
+		case *ast.CallExpr:
+			if acq, ok := f.trigger(rule, e); ok && acq.Result == 0 {
+				// Single value so there is no other result to check.
+				return acquired{Result: i, IfNil: -1}, true
+			}
+		case *ast.Ident:
+			acq, assign := f.assignedAcquisition(rule, body, f.pass.TypesInfo.ObjectOf(e))
+			if assign == nil {
+				continue
+			}
+
+			res := acquired{Result: i, IfNil: -1}
+			if acq.IfNil >= 0 {
+				res.IfNil = f.propagated(sig, results, assign, acq.IfNil, i)
+			}
+			return res, true
+		}
+	}
+
+	return acquired{}, false
+}
+
+// propagated returns the index of results which returns the result idx
+// of the acquisition assigned by assign, or if there is none the last
+// result of sig if it's an error other than the acquired result,
+// otherwise -1.
+func (f *facts) propagated(sig *types.Signature, results []ast.Expr, assign *ast.AssignStmt, idx, result int) int {
+	if idx < len(assign.Lhs) && len(assign.Rhs) == 1 {
+		if ident, ok := assign.Lhs[idx].(*ast.Ident); ok {
+			if obj := f.pass.TypesInfo.ObjectOf(ident); obj != nil {
+				for i, expr := range results {
+					if e, ok := astutil.Unparen(expr).(*ast.Ident); ok && f.pass.TypesInfo.ObjectOf(e) == obj {
+						return i
+					}
+				}
+			}
+		}
+	}
+
+	res := sig.Results()
+	if last := res.Len() - 1; last != result && types.Identical(res.At(last).Type(), errorType) {
+		return last
+	}
+
+	return -1
+}
+
+// assignedAcquisition returns the acquisition and the statement which
+// assigns obj a value carrying the obligation of rule in body, nil if
+// there is none.
+func (f *facts) assignedAcquisition(rule Rule, body *ast.BlockStmt, obj types.Object) (acquired, *ast.AssignStmt) {
+	if obj == nil {
+		return acquired{}, nil
+	}
+
+	var acq acquired
+	var found *ast.AssignStmt
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			return false // Different scope.
+		case *ast.AssignStmt:
+			for _, rhs := range n.Rhs {
+				call, ok := astutil.Unparen(rhs).(*ast.CallExpr)
+				if !ok {
+					continue
+				}
+
+				a, ok := f.trigger(rule, call)
+				if !ok {
+					continue
+				}
+
+				if ident := rootIdent(assigned(n, call, a.Result)); ident != nil && f.pass.TypesInfo.ObjectOf(ident) == obj {
+					acq, found = a, n
+				}
+			}
+		}
+		return found == nil
+	})
+
+	return acq, found
+}
+
+// releases returns the fact for fn, nil if it releases nothing.
+func (f *facts) releases(fn *types.Func) *releasesFact {
+	if fn.Pkg() != f.pass.Pkg {
+		fact := &releasesFact{}
+		if !f.pass.ImportObjectFact(fn, fact) {
+			return nil
+		}
+		return fact
+	}
+
+	if fact, ok := f.cache[fn]; ok {
+		return fact
+	}
+
+	decl, ok := f.decls[fn]
+	if !ok {
+		return nil // No body.
+	}
+
+	// Break recursive cycles by assuming nothing is released.
+	f.cache[fn] = nil
+
+	fact := &releasesFact{Params: make(map[string][]int)}
+	for _, r := range f.rules {
+		for _, rule := range r.obligations() {
+			f.paramsReleased(fact, rule, decl)
+		}
+	}
+
+	if len(fact.Params) == 0 {
+		return nil
+	}
+
+	f.log.Debug().
+		Stringer("func", fn).
+		Stringer("fact", fact).
+		Msg("export")
+	f.cache[fn] = fact
+	f.pass.ExportObjectFact(fn, fact)
+
+	return fact
+}
+
+// owns returns the fact for the method fn, nil if it's not an owner
+// method or releases no fields.
+func (f *facts) owns(fn *types.Func) *ownsFact {
+	if f.owner == nil || !f.owner(fn.Name()) {
+		return nil // Not an owner method.
+	}
+
+	if fn.Pkg() != f.pass.Pkg {
+		fact := &ownsFact{}
+		if !f.pass.ImportObjectFact(fn, fact) {
+			return nil
+		}
+		return fact
+	}
+
+	if fact, ok := f.owned[fn]; ok {
+		return fact
+	}
+
+	decl, ok := f.decls[fn]
+	if !ok || decl.Recv == nil || len(decl.Recv.List) == 0 || len(decl.Recv.List[0].Names) == 0 {
+		return nil // No body, not a method or unnamed receiver.
+	}
+
+	// Break recursive cycles by assuming nothing is released.
+	f.owned[fn] = nil
+
+	recv := fn.Type().(*types.Signature).Recv().Type() //nolint: forcetypeassert
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+
+	st, ok := recv.Underlying().(*types.Struct)
+	if !ok {
+		return nil // Not a struct so no fields.
+	}
+
+	fact := &ownsFact{Fields: make(map[string][]string)}
+	for _, r := range f.rules {
+		for _, rule := range r.obligations() {
+			for i := 0; i < st.NumFields(); i++ {
+				if field := st.Field(i); f.fieldReleased(rule, decl, field) {
+					fact.Fields[rule.id] = append(fact.Fields[rule.id], field.Name())
+				}
+			}
+		}
+	}
+
+	if len(fact.Fields) == 0 {
+		return nil
+	}
+
+	f.log.Debug().
+		Stringer("func", fn).
+		Stringer("fact", fact).
+		Msg("export")
+	f.owned[fn] = fact
+	f.pass.ExportObjectFact(fn, fact)
+
+	return fact
+}
+
+// fieldReleased returns true if the method decl calls the expected method
+// of rule on field of its receiver, false otherwise.
+func (f *facts) fieldReleased(rule Rule, decl *ast.FuncDecl, field *types.Var) bool {
+	if !rule.expects.match(field.Type()) {
+		return false // Not a field of the expected type.
+	}
+
+	v := newVisitor(f.pass, f.log, f, rule, decl.Recv.List[0].Names[0])
+	v.field = field.Name()
+	for _, stmt := range decl.Body.List {
+		if v.walk(stmt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// paramsReleased records in fact the parameters of decl which are
+// released by every path through it for rule.
+func (f *facts) paramsReleased(fact *releasesFact, rule Rule, decl *ast.FuncDecl) {
+	idx := 0
+	for _, field := range decl.Type.Params.List {
+		for _, name := range field.Names {
+			if f.paramReleased(rule, decl, field, name) {
+				fact.Params[rule.id] = append(fact.Params[rule.id], idx)
+			}
+			idx++
+		}
+		if len(field.Names) == 0 {
+			idx++ // Unnamed parameter.
+		}
+	}
+}
+
+// paramReleased returns true if every path through decl calls the
+// expected method of rule on the parameter name, false otherwise.
+func (f *facts) paramReleased(rule Rule, decl *ast.FuncDecl, field *ast.Field, name *ast.Ident) bool {
+	if name.Name == "_" {
+		return false
+	}
+
+	tv, ok := f.pass.TypesInfo.Types[field.Type]
+	if !ok || !rule.expects.match(tv.Type) {
+		return false // Not a parameter of the expected type.
+	}
+
+	fl := newFlow(f.pass, f.log, f, rule, name, decl.Body, f.cfgs.FuncDecl(decl))
+
+	return len(fl.leaksFrom(position{block: fl.graph.Blocks[0]})) == 0
+}
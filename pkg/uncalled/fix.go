@@ -0,0 +1,291 @@
+package uncalled
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// fixes returns the suggested fixes for rule not being called on ident
+// which was assigned by assign and checked by f.
+// Fixes are made in the block which declares ident, after the statement
+// containing assign, so they apply on every path through nested blocks.
+func (a *analyzer) fixes(rule Rule, f *flow, ident *ast.Ident, assign *ast.AssignStmt, stack []ast.Node) []analysis.SuggestedFix {
+	if ident.Name == "_" || initStmt(stack) != nil {
+		// Blank or assigned in a statement header with no line to insert after.
+		return nil
+	}
+
+	// Suggest the call of the obligation which specifies a fix.
+	rule.expect = rule.expect.fixer()
+	if rule.expect == nil {
+		return nil
+	}
+
+	blocks := enclosingBlocks(a.pass.TypesInfo, stack, a.pass.TypesInfo.ObjectOf(ident))
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	fix := rule.expect.Fix
+
+	switch fix.Type {
+	case fixDefer:
+		return a.deferFix(rule, f, ident.Name, assign, blocks)
+	case fixCheck:
+		return a.checkFix(rule, f, ident.Name, assign, blocks, stack)
+	default:
+		return nil
+	}
+}
+
+// deferFix returns a fix which inserts a deferred call of rule on name
+// after the statement of the outermost of blocks, or after the if-nil
+// guard which follows it.
+func (a *analyzer) deferFix(rule Rule, f *flow, name string, assign *ast.AssignStmt, blocks [][]ast.Stmt) []analysis.SuggestedFix {
+	call, ok := fixCall(rule, name, assign)
+	if !ok {
+		return nil
+	}
+
+	stmts := blocks[len(blocks)-1]
+
+	return a.insertAfter(afterGuard(f, stmts), stmts[0], fmt.Sprintf("Add defer %s", call), "defer "+call)
+}
+
+// checkFix returns a fix which inserts a check of the error returned by
+// the call of rule on name after the last statement in blocks which calls
+// the after method of the call on name, or if it has none the last loop
+// over name. If there is no such statement it's inserted after the
+// statement of the outermost of blocks or the if-nil guard which follows
+// it. No fix is returned if the last statement is a return, as the check
+// can't follow it.
+func (a *analyzer) checkFix(rule Rule, f *flow, name string, assign *ast.AssignStmt, blocks [][]ast.Stmt, stack []ast.Node) []analysis.SuggestedFix {
+	stmts := blocks[len(blocks)-1]
+	var node ast.Node = stmts[0]
+	pos := afterGuard(f, stmts)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		// Statements in outer blocks run after those in the blocks they contain.
+		if stmt := lastIteration(f, rule.expect, blocks[i]); stmt != nil {
+			if _, ok := stmt.(*ast.ReturnStmt); ok {
+				return nil // Iterates until the function returns.
+			}
+			node = stmt
+			pos = stmt.End()
+			break
+		}
+	}
+
+	call, ok := fixCall(rule, name, assign)
+	if !ok {
+		return nil
+	}
+
+	const errName = "err"
+	handle := "\t" + rule.expect.Fix.handler(errName)
+	if results, ok := a.errorResults(stack); ok {
+		handle = "\treturn " + strings.Join(append(results, errName), ", ")
+	}
+
+	return a.insertAfter(pos, node, fmt.Sprintf("Add check of %s", call),
+		fmt.Sprintf("if %s := %s; %s != nil {", errName, call, errName),
+		handle,
+		"}",
+	)
+}
+
+// fixCall returns the expected call of rule on name, with arguments
+// resolved from the results assigned by assign and the variables passed
+// to the triggering call, and true if it can be written, false otherwise.
+func fixCall(rule Rule, name string, assign *ast.AssignStmt) (string, bool) {
+	var refs, params []string
+	if len(assign.Rhs) == 1 {
+		for _, lhs := range assign.Lhs {
+			var ref string
+			if ident, ok := lhs.(*ast.Ident); ok {
+				ref = ident.Name
+			}
+			refs = append(refs, ref)
+		}
+
+		if call, ok := astutil.Unparen(assign.Rhs[0]).(*ast.CallExpr); ok {
+			for _, arg := range call.Args {
+				var param string
+				if ident, ok := astutil.Unparen(arg).(*ast.Ident); ok && ident.Name != "_" {
+					param = ident.Name
+				}
+				params = append(params, param)
+			}
+		}
+	}
+
+	args := make([]string, len(rule.expect.args))
+	for i, m := range rule.expect.args {
+		src, ok := m.source(refs, params)
+		if !ok {
+			return "", false // Argument value unknown.
+		}
+		args[i] = src
+	}
+
+	return fmt.Sprintf("%s%s(%s)", name, rule.expect.Call, strings.Join(args, ", ")), true
+}
+
+// afterGuard returns the position after the first of stmts, or if followed
+// by an if-nil guard, the position after that.
+func afterGuard(f *flow, stmts []ast.Stmt) token.Pos {
+	if len(stmts) > 1 {
+		if guard, ok := stmts[1].(*ast.IfStmt); ok && guard.Init == nil && f.guardCheck(guard.Cond) == token.NEQ {
+			return guard.End()
+		}
+	}
+
+	return stmts[0].End()
+}
+
+// lastIteration returns the last of stmts which calls the after method of
+// e on one of the idents f is interested in, or if e has none the last loop
+// over them, nil if there is none.
+func lastIteration(f *flow, e *Expect, stmts []ast.Stmt) ast.Stmt {
+	if e.After == "" {
+		if loop := lastLoop(f, stmts); loop != nil {
+			return loop
+		}
+		return nil
+	}
+
+	for i := len(stmts) - 1; i >= 0; i-- {
+		if f.visitor.methodCall(stmts[i], e.After) != nil {
+			return stmts[i]
+		}
+	}
+
+	return nil
+}
+
+// lastLoop returns the last for loop in stmts whose condition uses one of
+// the idents f is interested in, nil if there is none.
+func lastLoop(f *flow, stmts []ast.Stmt) *ast.ForStmt {
+	var last *ast.ForStmt
+	for _, stmt := range stmts {
+		loop, ok := stmt.(*ast.ForStmt)
+		if !ok || loop.Cond == nil {
+			continue
+		}
+
+		ast.Inspect(loop.Cond, func(node ast.Node) bool {
+			if expr, ok := node.(ast.Expr); ok && f.visitor.interested(expr) {
+				last = loop
+			}
+			return last != loop
+		})
+	}
+
+	return last
+}
+
+// errorResults returns the zero values of the results, other than the
+// last, of the innermost function in stack and true if its last result
+// is an error, otherwise false.
+func (a *analyzer) errorResults(stack []ast.Node) ([]string, bool) {
+	var sig *types.Signature
+	for i := len(stack) - 1; i >= 0 && sig == nil; i-- {
+		switch fn := stack[i].(type) {
+		case *ast.FuncLit:
+			sig, _ = a.pass.TypesInfo.TypeOf(fn).(*types.Signature)
+		case *ast.FuncDecl:
+			if obj := a.pass.TypesInfo.Defs[fn.Name]; obj != nil {
+				sig, _ = obj.Type().(*types.Signature)
+			}
+		}
+	}
+
+	if sig == nil {
+		return nil, false
+	}
+
+	res := sig.Results()
+	last := res.Len() - 1
+	if last < 0 || !types.Identical(res.At(last).Type(), errorType) {
+		return nil, false
+	}
+
+	zeros := make([]string, last)
+	for i := range zeros {
+		zeros[i] = a.zero(res.At(i).Type())
+	}
+
+	return zeros, true
+}
+
+// zero returns the zero value expression of t.
+func (a *analyzer) zero(t types.Type) string {
+	qualifier := func(p *types.Package) string {
+		if p == a.pass.Pkg {
+			return ""
+		}
+		return p.Name()
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsNumeric != 0:
+			return "0"
+		}
+	case *types.Struct, *types.Array:
+		return types.TypeString(t, qualifier) + "{}"
+	case *types.Interface:
+		if _, ok := t.(*types.TypeParam); ok {
+			return "*new(" + types.TypeString(t, qualifier) + ")"
+		}
+	}
+
+	return "nil"
+}
+
+// insertAfter returns a fix with message which inserts lines, indented
+// to match node, after the line containing pos.
+func (a *analyzer) insertAfter(pos token.Pos, node ast.Node, message string, lines ...string) []analysis.SuggestedFix {
+	file := a.pass.Fset.File(pos)
+	line := file.Line(pos)
+	if line >= file.LineCount() {
+		return nil // No following line to insert before.
+	}
+
+	var text strings.Builder
+	indent := a.indent(node)
+	for _, l := range lines {
+		text.WriteString(indent + l + "\n")
+	}
+
+	pos = file.LineStart(line + 1)
+
+	return []analysis.SuggestedFix{{
+		Message: message,
+		TextEdits: []analysis.TextEdit{{
+			Pos:     pos,
+			End:     pos,
+			NewText: []byte(text.String()),
+		}},
+	}}
+}
+
+// indent returns the indentation of node, assuming gofmt formatting.
+func (a *analyzer) indent(node ast.Node) string {
+	col := a.pass.Fset.Position(node.Pos()).Column
+	if col < 1 {
+		return ""
+	}
+
+	return strings.Repeat("\t", col-1)
+}
@@ -0,0 +1,330 @@
+package uncalled
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/cfg"
+)
+
+// flow searches the control-flow graph of a function for paths
+// from an acquisition to a function exit which don't call the
+// expected method.
+type flow struct {
+	// body is the body of the function being checked.
+	body *ast.BlockStmt
+
+	// graph is the control-flow graph of the function being checked.
+	graph *cfg.CFG
+
+	// guard is the variable which holds the result that must be nil for
+	// the call to be expected, nil if the call is always expected.
+	guard types.Object
+
+	// info is the type information for the package being checked.
+	info *types.Info
+
+	// visitor is used to check each node on a path.
+	visitor *visitor
+
+	// misses interns the expected calls which may not count, so paths
+	// through them are only visited once.
+	misses map[*ast.CallExpr]*miss
+
+	// log is the logger to use for debugging.
+	log zerolog.Logger
+}
+
+// newFlow returns a new flow which checks graph, of the function with
+// body, for calls matching rule on ident.
+func newFlow(pass *analysis.Pass, log zerolog.Logger, facts *facts, rule Rule, ident *ast.Ident, body *ast.BlockStmt, graph *cfg.CFG) *flow {
+	return &flow{
+		body:    body,
+		graph:   graph,
+		info:    pass.TypesInfo,
+		visitor: newVisitor(pass, log, facts, rule, ident),
+		log:     log,
+		misses:  make(map[*ast.CallExpr]*miss),
+	}
+}
+
+// guarded configures f so that paths on which guard is known to be
+// non nil are not required to make the call.
+func (f *flow) guarded(guard *ast.Ident) {
+	if guard == nil || guard.Name == "_" {
+		return // Never checked.
+	}
+
+	f.guard = f.info.ObjectOf(guard)
+}
+
+// miss is an expected call which may not count.
+type miss struct {
+	// call is the expected call.
+	call *ast.CallExpr
+
+	// expect is the expectation call matched.
+	expect *Expect
+
+	// ignored is true if the call doesn't count as its result was
+	// discarded, otherwise it only counts if the method it must be
+	// after isn't called after it.
+	ignored bool
+}
+
+// miss returns the interned miss of the expected call found by the
+// visitor.
+func (f *flow) miss() *miss {
+	m, ok := f.misses[f.visitor.call]
+	if !ok {
+		m = &miss{call: f.visitor.call, expect: f.visitor.expect, ignored: f.visitor.ignored}
+		f.misses[m.call] = m
+	}
+
+	return m
+}
+
+// exit is a function exit which is reached without calling the
+// expected method.
+type exit struct {
+	*ast.ReturnStmt
+
+	// kind describes the exit.
+	kind string
+
+	// missed is the last expected call on the path which didn't count,
+	// nil if none.
+	missed *miss
+}
+
+// position is a node in the control-flow graph.
+type position struct {
+	block *cfg.Block
+	idx   int
+
+	// guarded is true if the guard still holds the result of
+	// the acquisition on this path.
+	guarded bool
+
+	// pending is the expected call made on this path which counts if
+	// the method it must be after isn't called after it, nil if none.
+	pending *miss
+
+	// missed is the last expected call on this path which didn't count,
+	// nil if none.
+	missed *miss
+}
+
+// key returns the key used to track visited positions.
+func (p position) key() position {
+	return position{block: p.block, guarded: p.guarded, pending: p.pending, missed: p.missed}
+}
+
+// find returns the position of node in the control-flow graph
+// and true if found, false otherwise.
+func (f *flow) find(node ast.Node) (position, bool) {
+	for _, b := range f.graph.Blocks {
+		if !b.Live {
+			continue // Unreachable.
+		}
+
+		for i, n := range b.Nodes {
+			if n == node {
+				return position{block: b, idx: i}, true
+			}
+		}
+	}
+
+	return position{}, false
+}
+
+// leaks returns the exits reachable from the node after start
+// without calling the expected method, ordered by position.
+// If start is not part of the control-flow graph nil is returned.
+func (f *flow) leaks(start ast.Node) []exit {
+	pos, ok := f.find(start)
+	if !ok {
+		f.log.Debug().Msg("start not found in control-flow graph")
+		return nil
+	}
+
+	pos.idx++
+
+	return f.leaksFrom(pos)
+}
+
+// leaksFrom returns the exits reachable from pos without calling
+// the expected method, ordered by position.
+func (f *flow) leaksFrom(pos position) []exit {
+	var exits []exit
+	seen := make(map[position]struct{})
+	pos.guarded = f.guard != nil
+	work := []position{pos}
+	for len(work) > 0 {
+		p := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		nodes := p.block.Nodes[p.idx:]
+		if f.called(&p) {
+			continue // Path satisfied.
+		}
+
+		if p.guarded && f.assigns(nodes) {
+			p.guarded = false // Guard no longer holds our result.
+		}
+
+		if len(p.block.Succs) == 0 {
+			// Blocks without a return end in a call which never
+			// returns, such as panic, so don't leak.
+			if ret := p.block.Return(); ret != nil && p.pending == nil {
+				e := f.exit(ret)
+				e.missed = p.missed
+				exits = append(exits, e)
+			}
+			continue
+		}
+
+		skip := -1
+		if p.guarded {
+			skip = f.nonNilSucc(p.block)
+		}
+
+		for i, b := range p.block.Succs {
+			if i == skip {
+				continue // Guard is non nil so call is not expected.
+			}
+
+			next := position{block: b, guarded: p.guarded, pending: p.pending, missed: p.missed}
+			if _, ok := seen[next.key()]; ok {
+				continue // Already visited.
+			}
+			seen[next.key()] = struct{}{}
+			work = append(work, next)
+		}
+	}
+
+	sort.Slice(exits, func(i, j int) bool {
+		return exits[i].Pos() < exits[j].Pos()
+	})
+
+	return exits
+}
+
+// exit returns the exit for ret.
+func (f *flow) exit(ret *ast.ReturnStmt) exit {
+	if ret.Return == f.body.Rbrace {
+		// Implicit return added by the control-flow graph.
+		return exit{ReturnStmt: ret, kind: "end of function"}
+	}
+
+	return exit{ReturnStmt: ret, kind: "return"}
+}
+
+// called returns true if any of the nodes from p calls the expected
+// method, false otherwise. Calls which may not count are tracked in p.
+func (f *flow) called(p *position) bool {
+	for _, n := range p.block.Nodes[p.idx:] {
+		if p.pending != nil && f.visitor.methodCall(n, p.pending.expect.After) != nil {
+			p.missed, p.pending = p.pending, nil // Called too early.
+		}
+
+		if !f.visitor.walk(n) {
+			if f.visitor.ignored {
+				p.missed = f.miss() // Result discarded.
+			}
+			continue
+		}
+
+		if _, ok := n.(*ast.DeferStmt); ok || f.visitor.expect == nil || f.visitor.expect.After == "" {
+			return true // Deferred, transferred or unordered.
+		}
+
+		p.pending = f.miss()
+	}
+
+	return false
+}
+
+// assigns returns true if any of nodes assigns to the guard,
+// false otherwise.
+func (f *flow) assigns(nodes []ast.Node) bool {
+	for _, n := range nodes {
+		stmt, ok := n.(*ast.AssignStmt)
+		if !ok {
+			continue
+		}
+
+		for _, lhs := range stmt.Lhs {
+			if ident, ok := lhs.(*ast.Ident); ok && f.info.ObjectOf(ident) == f.guard {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// nonNilSucc returns the index of the successor of block which is only
+// reached when the guard is non nil, or -1 if there is none.
+func (f *flow) nonNilSucc(block *cfg.Block) int {
+	if len(block.Nodes) == 0 || len(block.Succs) != 2 {
+		return -1 // Not a conditional block.
+	}
+
+	expr, ok := block.Nodes[len(block.Nodes)-1].(ast.Expr)
+	if !ok {
+		return -1 // Not a condition.
+	}
+
+	switch f.guardCheck(expr) {
+	case token.NEQ:
+		return 0 // True branch.
+	case token.EQL:
+		return 1 // False branch.
+	default:
+		return -1
+	}
+}
+
+// guardCheck returns the operator if expr compares the guard against nil
+// using == or !=, otherwise token.ILLEGAL.
+func (f *flow) guardCheck(expr ast.Expr) token.Token {
+	if f.guard == nil {
+		return token.ILLEGAL
+	}
+
+	cond, ok := astutil.Unparen(expr).(*ast.BinaryExpr)
+	if !ok || (cond.Op != token.NEQ && cond.Op != token.EQL) {
+		return token.ILLEGAL // Not a comparison.
+	}
+
+	if !f.isGuard(cond.X) || !f.isNil(cond.Y) {
+		if !f.isGuard(cond.Y) || !f.isNil(cond.X) {
+			return token.ILLEGAL // Not a comparison of guard against nil.
+		}
+	}
+
+	return cond.Op
+}
+
+// isGuard returns true if expr is the guard, false otherwise.
+func (f *flow) isGuard(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && f.info.ObjectOf(ident) == f.guard
+}
+
+// isNil returns true if expr is the predeclared nil, false otherwise.
+func (f *flow) isNil(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	_, ok = f.info.ObjectOf(ident).(*types.Nil)
+	return ok
+}
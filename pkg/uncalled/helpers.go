@@ -2,22 +2,15 @@ package uncalled
 
 import (
 	"go/ast"
+	"go/token"
 	"go/types"
 	"io"
+	"strings"
 
 	"github.com/rs/zerolog"
+	"golang.org/x/tools/go/ast/astutil"
 )
 
-// containsType returns true if typ not nil and contained in rowTypes, false otherwise.
-func containsType(typ types.Type, rowTypes map[string]struct{}) bool {
-	if typ == nil {
-		return false
-	}
-
-	_, ok := rowTypes[typ.String()]
-	return ok
-}
-
 // rootIdent finds the root identifier x in a chain of selections x.y.z, or nil if not found.
 func rootIdent(node ast.Node) *ast.Ident {
 	switch node := node.(type) {
@@ -42,21 +35,204 @@ func names(node ast.Node) []string {
 	}
 }
 
-// restOfBlock, given a traversal stack, finds the innermost containing block
-// and returns the suffix of its statements starting with the current node.
+// returns returns true if call is one of the results of stmt, false otherwise.
+func returns(stmt *ast.ReturnStmt, call *ast.CallExpr) bool {
+	for _, expr := range stmt.Results {
+		if astutil.Unparen(expr) == call {
+			return true
+		}
+	}
+
+	return false
+}
+
+// assigned returns the expression which result idx of call is
+// assigned to by stmt, or nil if it isn't assigned.
+func assigned(stmt *ast.AssignStmt, call *ast.CallExpr, idx int) ast.Expr {
+	if len(stmt.Rhs) == 1 && len(stmt.Lhs) > 1 {
+		// Tuple assignment: a, b := f()
+		if astutil.Unparen(stmt.Rhs[0]) != call || idx >= len(stmt.Lhs) {
+			return nil
+		}
+		return stmt.Lhs[idx]
+	}
+
+	if idx != 0 {
+		return nil // Single value assignments only assign result 0.
+	}
+
+	for i, rhs := range stmt.Rhs {
+		if astutil.Unparen(rhs) == call && i < len(stmt.Lhs) {
+			return stmt.Lhs[i]
+		}
+	}
+
+	return nil
+}
+
+// selectorAt returns the expression formed by the first n+1 names of the chain
+// of selections x.y.z, for example n of 1 returns x.y.
+func selectorAt(node ast.Node, n int) ast.Expr {
+	expr, ok := node.(ast.Expr)
+	if !ok {
+		return nil
+	}
+
+	for i := len(names(node)) - 1; i > n; i-- {
+		sel, ok := expr.(*ast.SelectorExpr)
+		if !ok {
+			return nil
+		}
+		expr = sel.X
+	}
+
+	return expr
+}
+
+// structField returns the field of st named by key, nil if not found.
+func structField(st *types.Struct, key ast.Expr) *types.Var {
+	ident, ok := key.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Name() == ident.Name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// valueSpec, given a traversal stack, returns the var declaration spec
+// containing the current node, nil if there is none or it's within a
+// statement of a function literal initialiser.
+func valueSpec(stack []ast.Node) *ast.ValueSpec {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch n := stack[i].(type) {
+		case *ast.ValueSpec:
+			return n
+		case ast.Stmt:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// assignment returns node if it's an assignment, or an equivalent one if
+// it's a var declaration spec, so both can be checked the same way.
+func assignment(node ast.Node) *ast.AssignStmt {
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		return n
+	case *ast.ValueSpec:
+		lhs := make([]ast.Expr, len(n.Names))
+		for i, name := range n.Names {
+			lhs[i] = name
+		}
+		return &ast.AssignStmt{Lhs: lhs, Tok: token.DEFINE, Rhs: n.Values}
+	default:
+		return nil
+	}
+}
+
+// initStmt, given a traversal stack, returns the innermost containing
+// statement if it's the init statement of an if, switch or for statement,
+// otherwise nil.
+func initStmt(stack []ast.Node) ast.Stmt {
+	for i := len(stack) - 1; i > 0; i-- {
+		stmt, ok := stack[i].(ast.Stmt)
+		if !ok {
+			continue
+		}
+
+		var init ast.Stmt
+		switch s := stack[i-1].(type) {
+		case *ast.IfStmt:
+			init = s.Init
+		case *ast.SwitchStmt:
+			init = s.Init
+		case *ast.TypeSwitchStmt:
+			init = s.Init
+		case *ast.ForStmt:
+			init = s.Init
+		}
+
+		if init != stmt {
+			return nil
+		}
+
+		return init
+	}
+
+	return nil
+}
+
+// stmtList returns the statements of node and true if it's a block, or a
+// case or comm clause, otherwise false.
+func stmtList(node ast.Node) ([]ast.Stmt, bool) {
+	switch n := node.(type) {
+	case *ast.BlockStmt:
+		return n.List, true
+	case *ast.CaseClause:
+		return n.Body, true
+	case *ast.CommClause:
+		return n.Body, true
+	default:
+		return nil, false
+	}
+}
+
+// restOfBlock, given a traversal stack, finds the innermost containing block,
+// or case or comm clause, and returns the suffix of its statements starting
+// with the current node.
 func restOfBlock(stack []ast.Node) []ast.Stmt {
 	for i := len(stack) - 1; i >= 0; i-- {
-		if b, ok := stack[i].(*ast.BlockStmt); ok {
-			for j, v := range b.List {
+		list, ok := stmtList(stack[i])
+		if !ok {
+			continue
+		}
+
+		for j, v := range list {
+			if v == stack[i+1] {
+				return list[j:]
+			}
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// enclosingBlocks, given a traversal stack, returns the suffixes of the
+// statement lists containing the current node, starting with the one
+// containing it, innermost first, up to the one in the scope which declares
+// obj or the function body.
+func enclosingBlocks(info *types.Info, stack []ast.Node, obj types.Object) [][]ast.Stmt {
+	var blocks [][]ast.Stmt
+	for i := len(stack) - 2; i >= 0; i-- {
+		switch stack[i].(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			return blocks // Function scope.
+		}
+
+		if list, ok := stmtList(stack[i]); ok {
+			for j, v := range list {
 				if v == stack[i+1] {
-					return b.List[j:]
+					blocks = append(blocks, list[j:])
+					break
 				}
 			}
-			return nil
+		}
+
+		if obj != nil && info.Scopes[stack[i]] == obj.Parent() {
+			return blocks // Declaring scope.
 		}
 	}
 
-	return nil
+	return blocks
 }
 
 // newConsoleWriter returns a new zerolog.ConsoleWriter that writes to w with
@@ -69,3 +245,168 @@ func newConsoleWriter(w io.Writer) zerolog.ConsoleWriter {
 		},
 	}
 }
+
+// lookupType returns the type name qualified by its package path, for
+// example io.Closer, from pkg or the packages it imports transitively,
+// or from the universe if unqualified, nil if not found.
+func lookupType(pkg *types.Package, name string) *types.TypeName {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		obj, _ := types.Universe.Lookup(name).(*types.TypeName)
+		return obj
+	}
+
+	path := name[:i]
+	seen := map[*types.Package]struct{}{pkg: {}}
+	work := []*types.Package{pkg}
+	for len(work) > 0 {
+		p := work[len(work)-1]
+		work = work[:len(work)-1]
+		if matchesPath(path, p.Path()) {
+			obj, _ := p.Scope().Lookup(name[i+1:]).(*types.TypeName)
+			return obj
+		}
+
+		for _, imp := range p.Imports() {
+			if _, ok := seen[imp]; !ok {
+				seen[imp] = struct{}{}
+				work = append(work, imp)
+			}
+		}
+	}
+
+	return nil
+}
+
+// lookupTypeExpr returns the type named by expr, optionally a pointer to
+// or slice of a named type, for example *example.com/pkg.Type, found from
+// pkg as by lookupType, nil if not found or not a type of that form.
+func lookupTypeExpr(pkg *types.Package, expr string) types.Type {
+	switch {
+	case strings.HasPrefix(expr, "*"):
+		if elem := lookupTypeExpr(pkg, expr[1:]); elem != nil {
+			return types.NewPointer(elem)
+		}
+	case strings.HasPrefix(expr, "[]"):
+		if elem := lookupTypeExpr(pkg, expr[2:]); elem != nil {
+			return types.NewSlice(elem)
+		}
+	default:
+		if obj := lookupType(pkg, expr); obj != nil {
+			return obj.Type()
+		}
+	}
+
+	return nil
+}
+
+// typePackages returns the paths of the packages which declare the named
+// types used by the expressions in info.
+func typePackages(info *types.Info) []string {
+	var pkgs []string
+	added := make(map[string]struct{})
+	seen := make(map[types.Type]struct{})
+	var add func(t types.Type)
+	add = func(t types.Type) {
+		if t == nil {
+			return
+		}
+
+		if _, ok := seen[t]; ok {
+			return // Already added.
+		}
+		seen[t] = struct{}{}
+
+		switch t := t.(type) {
+		case *types.Named:
+			if pkg := t.Obj().Pkg(); pkg != nil {
+				if _, ok := added[pkg.Path()]; !ok {
+					added[pkg.Path()] = struct{}{}
+					pkgs = append(pkgs, pkg.Path())
+				}
+			}
+			for i := 0; i < t.TypeArgs().Len(); i++ {
+				add(t.TypeArgs().At(i))
+			}
+		case *types.Pointer:
+			add(t.Elem())
+		case *types.Slice:
+			add(t.Elem())
+		case *types.Array:
+			add(t.Elem())
+		case *types.Chan:
+			add(t.Elem())
+		case *types.Map:
+			add(t.Key())
+			add(t.Elem())
+		case *types.Signature:
+			add(t.Params())
+			add(t.Results())
+		case *types.Tuple:
+			for i := 0; i < t.Len(); i++ {
+				add(t.At(i).Type())
+			}
+		}
+	}
+
+	for _, tv := range info.Types {
+		add(tv.Type)
+	}
+
+	return pkgs
+}
+
+// matchesPath returns true if the package path matches the import path
+// pattern, false otherwise. Vendored packages match their import path and
+// the packages of a major version of a module match the module path
+// without its major version suffix, so example.com/mod matches
+// example.com/mod/v2 but example.com/mod/v2 doesn't match example.com/mod/v3.
+func matchesPath(pattern, path string) bool {
+	path = unvendor(path)
+	return path == pattern || majorless(path) == pattern
+}
+
+// matchesAny returns true if any of paths matches the import path
+// pattern, false otherwise.
+func matchesAny(pattern string, paths []string) bool {
+	for _, p := range paths {
+		if matchesPath(pattern, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// majorless returns path without any trailing major version element,
+// for example example.com/mod for example.com/mod/v2.
+func majorless(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i < 0 || !reMajor.MatchString(path[i+1:]) {
+		return path
+	}
+
+	return path[:i]
+}
+
+// unvendor returns path without any vendor prefix, so vendored packages
+// match their import path.
+func unvendor(path string) string {
+	if i := strings.LastIndex(path, "/vendor/"); i >= 0 {
+		return path[i+len("/vendor/"):]
+	}
+
+	return strings.TrimPrefix(path, "vendor/")
+}
+
+// unalias returns t with any aliases resolved. Newer versions of go/types
+// represent aliases explicitly, so this detects them by their method.
+func unalias(t types.Type) types.Type {
+	for {
+		alias, ok := t.(interface{ Rhs() types.Type })
+		if !ok {
+			return t
+		}
+		t = alias.Rhs()
+	}
+}
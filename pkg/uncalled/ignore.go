@@ -0,0 +1,217 @@
+package uncalled
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+const (
+	// ignorePrefix is the prefix of an uncalled ignore directive.
+	ignorePrefix = "//uncalled:ignore"
+
+	// nolintPrefix is the prefix of a nolint directive.
+	nolintPrefix = "//nolint"
+
+	// directiveCategory is the category used to report directive issues.
+	directiveCategory = "directive"
+)
+
+// directive is an inline suppression directive.
+type directive struct {
+	// comment is the comment containing the directive.
+	comment *ast.Comment
+
+	// from and to are the range of positions suppressed.
+	from, to token.Pos
+
+	// rules maps the rules suppressed to true if used, nil if all
+	// rules are suppressed.
+	rules map[string]bool
+
+	// used is true if the directive suppressed a report of any rule.
+	used bool
+
+	// all is true if the directive applies to all linters.
+	all bool
+}
+
+// suppresses returns true if d suppresses a report of rule at pos,
+// marking it as used, false otherwise.
+func (d *directive) suppresses(rule string, pos token.Pos) bool {
+	if pos < d.from || pos >= d.to {
+		return false // Out of scope.
+	}
+
+	if d.rules != nil {
+		if _, ok := d.rules[rule]; !ok {
+			return false // Rule not listed.
+		}
+		d.rules[rule] = true
+	}
+	d.used = true
+
+	return true
+}
+
+// unused returns the rules listed by d which suppressed nothing.
+func (d *directive) unused() []string {
+	if d.rules == nil {
+		if d.used {
+			return nil
+		}
+		return []string{name}
+	}
+
+	var rules []string
+	for rule, used := range d.rules {
+		if !used {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Strings(rules)
+
+	return rules
+}
+
+// parseDirectives returns the suppression directives in the files of
+// the current package, reporting any which are malformed.
+func (a *analyzer) parseDirectives() []*directive {
+	var dirs []*directive
+	for _, file := range a.pass.Files {
+		tf := a.pass.Fset.File(file.Pos())
+		if tf == nil {
+			continue
+		}
+
+		funcDocs := make(map[*ast.CommentGroup]*ast.FuncDecl)
+		for _, decl := range file.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Doc != nil {
+				funcDocs[fd.Doc] = fd
+			}
+		}
+
+		fileStart := token.Pos(tf.Base())
+		fileEnd := fileStart + token.Pos(tf.Size())
+		for _, cg := range file.Comments {
+			for _, c := range cg.List {
+				d := a.parseDirective(c)
+				if d == nil {
+					continue
+				}
+
+				switch fd, ok := funcDocs[cg]; {
+				case c.End() < file.Package:
+					// File header.
+					d.from, d.to = fileStart, fileEnd
+				case ok:
+					// Function doc comment.
+					d.from, d.to = fd.Pos(), fd.End()
+				default:
+					// Line.
+					line := tf.Line(c.Pos())
+					d.from, d.to = tf.LineStart(line), fileEnd
+					if line < tf.LineCount() {
+						d.to = tf.LineStart(line + 1)
+					}
+				}
+				dirs = append(dirs, d)
+			}
+		}
+	}
+
+	return dirs
+}
+
+// parseDirective returns the directive in c, nil if c isn't a directive
+// for this analyzer.
+func (a *analyzer) parseDirective(c *ast.Comment) *directive {
+	text := c.Text
+	if i := strings.Index(text[2:], "//"); i >= 0 {
+		// Trailing comment.
+		text = text[:i+2]
+	}
+
+	switch {
+	case strings.HasPrefix(text, ignorePrefix):
+		fields := strings.Fields(strings.TrimPrefix(text, ignorePrefix))
+		if len(fields) < 2 || !strings.HasPrefix(text, ignorePrefix+" ") {
+			a.publish(directiveCategory, analysis.Diagnostic{
+				Pos:      c.Pos(),
+				End:      c.End(),
+				Category: directiveCategory,
+				Message:  fmt.Sprintf("%s directive must specify rules and a reason", strings.TrimPrefix(ignorePrefix, "//")),
+			})
+			return nil
+		}
+
+		d := &directive{comment: c, rules: make(map[string]bool)}
+		for _, rule := range strings.Split(fields[0], ",") {
+			d.rules[rule] = false
+		}
+		return d
+	case strings.HasPrefix(text, nolintPrefix):
+		rest := strings.TrimPrefix(text, nolintPrefix)
+		if strings.TrimSpace(rest) == "" || rest[0] == ' ' {
+			// Applies to all linters.
+			return &directive{comment: c, all: true}
+		}
+
+		if rest[0] != ':' {
+			return nil // Not a nolint directive.
+		}
+
+		linters := strings.Fields(rest[1:])
+		if len(linters) == 0 {
+			return nil
+		}
+
+		for _, l := range strings.Split(linters[0], ",") {
+			if l == name {
+				return &directive{comment: c}
+			}
+		}
+	}
+
+	return nil
+}
+
+// suppressed returns true if a report of rule at pos is suppressed by
+// a directive, false otherwise.
+func (a *analyzer) suppressed(rule string, pos token.Pos) bool {
+	var suppressed bool
+	for _, d := range a.directives {
+		// Check all so every matching directive is marked used.
+		if d.suppresses(rule, pos) {
+			suppressed = true
+		}
+	}
+
+	return suppressed
+}
+
+// reportUnusedIgnores reports directives which suppressed nothing.
+// Bare nolint directives are skipped as they apply to other linters.
+func (a *analyzer) reportUnusedIgnores() {
+	for _, d := range a.directives {
+		if d.all {
+			continue // Bare nolint.
+		}
+
+		unused := d.unused()
+		if len(unused) == 0 {
+			continue
+		}
+
+		a.publish(directiveCategory, analysis.Diagnostic{
+			Pos:      d.comment.Pos(),
+			End:      d.comment.End(),
+			Category: directiveCategory,
+			Message:  fmt.Sprintf("directive for %s does not suppress anything", strings.Join(unused, ",")),
+		})
+	}
+}
@@ -1,9 +1,18 @@
 package uncalled
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 
 	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
 )
 
 // loader creates a new analyser to process each call to its
@@ -15,19 +24,51 @@ type loader struct {
 	options []Option
 	log     log
 	id      atomic.Int32
+
+	// unusedIgnores is set by the unused-ignores flag.
+	unusedIgnores bool
+
+	// baseline is set by the baseline flag.
+	baseline *baseline
+
+	// record is set by the write-baseline flag.
+	record *baseline
+
+	// format is set by the format flag.
+	format string
+
+	// output is set by the o flag.
+	output string
+
+	// sarif is the report shared by all passes when format is sarif.
+	sarif     *sarifReport
+	sarifErr  error
+	sarifOnce sync.Once
+
+	// recorded is the baseline findings are recorded to, if any.
+	recorded atomic.Pointer[baseline]
+
+	// total is the number of packages the driver runs us on, zero if
+	// unknown, and done is the number which have been run.
+	total     int
+	totalErr  error
+	totalOnce sync.Once
+	done      atomic.Int32
 }
 
 // run creates an analyzer and calls run on it.
 func (l *loader) run(pass *analysis.Pass) (interface{}, error) {
+	l.totalOnce.Do(func() {
+		l.total, l.totalErr = countPackages()
+	})
+	if l.totalErr != nil {
+		return nil, l.totalErr
+	}
+
 	// Order of options is important, ours need to go first.
 	opts := make([]Option, 0, len(l.options)+2)
 	if l.cfg != nil {
-		// Take a copy to prevent data races.
-		cfg, err := l.cfg.copy()
-		if err != nil {
-			return nil, err
-		}
-		opts = append(opts, ConfigOpt(cfg))
+		opts = append(opts, ConfigOpt(l.cfg))
 	}
 
 	opts = append(opts, logger(l.log.
@@ -36,6 +77,14 @@ func (l *loader) run(pass *analysis.Pass) (interface{}, error) {
 		Logger(),
 	))
 
+	if l.unusedIgnores {
+		opts = append(opts, UnusedIgnores(true))
+	}
+
+	if l.baseline != nil || l.record != nil {
+		opts = append(opts, baselines(l.baseline, l.record))
+	}
+
 	opts = append(opts, l.options...)
 
 	a, err := newAnalyzer(opts...)
@@ -43,7 +92,113 @@ func (l *loader) run(pass *analysis.Pass) (interface{}, error) {
 		return nil, err
 	}
 
-	return a.run(pass)
+	if l.format == formatSARIF {
+		if l.output == "" {
+			return nil, errors.New("sarif format requires an output file")
+		}
+
+		l.sarifOnce.Do(func() {
+			l.sarif, l.sarifErr = newSARIFReport(l.output, a.cfg)
+		})
+		if l.sarifErr != nil {
+			return nil, l.sarifErr
+		}
+		a.sarif = l.sarif
+	}
+
+	if a.sarif != nil || a.record != nil {
+		a.root = root(pass)
+	}
+
+	if a.record != nil {
+		l.recorded.Store(a.record)
+	}
+
+	res, err := a.run(pass)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(l.done.Add(1)) == l.total {
+		// Last package so write our reports.
+		return res, l.finish()
+	}
+
+	return res, nil
+}
+
+// finish writes the SARIF report and baseline if configured.
+// It must only be called once all passes have run.
+func (l *loader) finish() error {
+	if l.sarif != nil {
+		if err := l.sarif.write(); err != nil {
+			return err
+		}
+	}
+
+	if b := l.recorded.Load(); b != nil {
+		return b.write()
+	}
+
+	return nil
+}
+
+// countPackages returns the number of packages the command line driver
+// runs us on, which are those it loads for its arguments and their
+// dependencies, or zero if we're run by another driver, such as
+// analysistest, which doesn't finish the run.
+func countPackages() (int, error) {
+	test := flag.Lookup("test")
+	if test == nil || flag.NArg() == 0 {
+		return 0, nil
+	}
+
+	tests, err := strconv.ParseBool(test.Value.String())
+	if err != nil {
+		return 0, fmt.Errorf("parse test flag: %w", err)
+	}
+
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedImports | packages.NeedDeps,
+		Tests: tests,
+	}
+	pkgs, err := packages.Load(cfg, flag.Args()...)
+	if err != nil {
+		return 0, fmt.Errorf("load packages: %w", err)
+	}
+
+	var n int
+	packages.Visit(pkgs, nil, func(*packages.Package) {
+		n++
+	})
+
+	return n, nil
+}
+
+// root returns true if pass is of a package below the working directory,
+// false otherwise.
+// Dependencies, such as those in the module cache or GOROOT, are analysed
+// to compute facts but the driver doesn't report their diagnostics, so
+// neither should our outputs. This uses the location of the files rather
+// than the packages requested, so the command line isn't loaded again.
+func root(pass *analysis.Pass) bool {
+	wd, err := os.Getwd()
+	if err != nil {
+		return true // Unknown so include.
+	}
+
+	for _, file := range pass.Files {
+		tf := pass.Fset.File(file.Pos())
+		if tf == nil {
+			continue
+		}
+
+		if rel, err := filepath.Rel(wd, tf.Name()); err == nil && !strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+
+	return false
 }
 
 // String implements flag.Value.
@@ -60,3 +215,26 @@ func (l *loader) Set(file string) error {
 	l.cfg = &Config{}
 	return l.cfg.loadFile(file)
 }
+
+// setBaseline loads the baseline file.
+func (l *loader) setBaseline(file string) (err error) {
+	l.baseline, err = loadBaseline(file)
+	return err
+}
+
+// setWriteBaseline sets the baseline file to record to.
+func (l *loader) setWriteBaseline(file string) error {
+	l.record = createBaseline(file)
+	return nil
+}
+
+// setFormat validates and sets the output format.
+func (l *loader) setFormat(format string) error {
+	switch format {
+	case formatText, formatSARIF:
+		l.format = format
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -0,0 +1,328 @@
+package uncalled
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+const (
+	// formatText is the output format which reports text via the driver.
+	formatText = "text"
+
+	// formatSARIF is the output format which additionally writes a
+	// SARIF report.
+	formatSARIF = "sarif"
+
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifURI     = "https://github.com/stevenh/go-uncalled"
+)
+
+//go:embed RULES.md
+var rulesDoc string
+
+// reSlug matches the characters replaced when creating a heading anchor.
+var reSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// sarifLog is the top level object of a SARIF file.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// sarifRun is a single run of an analysis tool.
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+// sarifTool describes the analysis tool.
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+// sarifDriver describes the analysis tool component and its rules.
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+// sarifRule describes a rule.
+type sarifRule struct {
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name"`
+	ShortDescription sarifMessage           `json:"shortDescription"`
+	Help             *sarifMessage          `json:"help,omitempty"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+// sarifMessage is a message with optional markdown.
+type sarifMessage struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+// sarifResult is a single finding.
+type sarifResult struct {
+	RuleID           string          `json:"ruleId"`
+	RuleIndex        *int            `json:"ruleIndex,omitempty"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+	Fixes            []sarifFix      `json:"fixes,omitempty"`
+}
+
+// sarifLocation is a location in an artifact.
+type sarifLocation struct {
+	ID               int                   `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+// sarifPhysicalLocation is a region of an artifact.
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+// sarifArtifactLocation identifies an artifact.
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifRegion is a region of an artifact, columns are one based.
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// sarifFix is a suggested fix.
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+// sarifArtifactChange is the change to a single artifact made by a fix.
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+// sarifReplacement replaces a region of an artifact.
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+// sarifReport is a SARIF report which is safe for concurrent use.
+type sarifReport struct {
+	file  string
+	mtx   sync.Mutex
+	log   sarifLog
+	index map[string]int
+}
+
+// newSARIFReport returns a new SARIF report to be written to file which
+// describes the rules of cfg.
+func newSARIFReport(file string, cfg *Config) (*sarifReport, error) {
+	driver := sarifDriver{
+		Name:           name,
+		Version:        version{}.String(),
+		InformationURI: sarifURI,
+	}
+
+	sections := ruleSections(rulesDoc)
+	for _, r := range cfg.Rules {
+		rule := cfg.rules[r.Name]
+		obligations := rule.obligations()
+		names := make([]string, len(obligations))
+		for i, ob := range obligations {
+			names[i] = ob.name("")
+		}
+		desc := fmt.Sprintf("%s must be called", strings.Join(names, " and "))
+		sr := sarifRule{
+			ID:               rule.Name,
+			Name:             rule.Name,
+			ShortDescription: sarifMessage{Text: desc},
+			Properties:       map[string]interface{}{"category": rule.Category},
+		}
+		if help, ok := sections[rule.Name]; ok {
+			sr.Help = &sarifMessage{Text: help, Markdown: help}
+		}
+		driver.Rules = append(driver.Rules, sr)
+	}
+
+	driver.Rules = append(driver.Rules, sarifRule{
+		ID:               directiveCategory,
+		Name:             directiveCategory,
+		ShortDescription: sarifMessage{Text: "ignore directives must be valid and suppress an issue"},
+		Properties:       map[string]interface{}{"category": directiveCategory},
+	})
+
+	r := &sarifReport{
+		file: file,
+		log: sarifLog{
+			Version: sarifVersion,
+			Schema:  sarifSchema,
+			Runs: []sarifRun{{
+				Tool:    sarifTool{Driver: driver},
+				Results: []sarifResult{},
+			}},
+		},
+		index: make(map[string]int, len(driver.Rules)),
+	}
+
+	for i, rule := range driver.Rules {
+		r.index[rule.ID] = i
+	}
+
+	return r, nil
+}
+
+// add adds results to the report, referencing their rules by index
+// if they're described by the report.
+func (r *sarifReport) add(results []sarifResult) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	run := &r.log.Runs[0]
+	for _, res := range results {
+		if i, ok := r.index[res.RuleID]; ok {
+			res.RuleIndex = &i
+		}
+		run.Results = append(run.Results, res)
+	}
+}
+
+// write writes the report to its file.
+func (r *sarifReport) write() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	data, err := json.MarshalIndent(r.log, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal sarif: %w", err)
+	}
+
+	if err := os.WriteFile(r.file, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write sarif: %w", err)
+	}
+
+	return nil
+}
+
+// ruleSections returns the sections of the markdown doc keyed by the
+// anchor of their heading.
+func ruleSections(doc string) map[string]string {
+	sections := make(map[string]string)
+	var anchor string
+	var body []string
+	flush := func() {
+		if anchor != "" {
+			sections[anchor] = strings.TrimSpace(strings.Join(body, "\n"))
+		}
+	}
+
+	for _, line := range strings.Split(doc, "\n") {
+		if strings.HasPrefix(line, "## ") {
+			flush()
+			heading := strings.ToLower(strings.TrimPrefix(line, "## "))
+			anchor = strings.Trim(reSlug.ReplaceAllString(heading, "-"), "-")
+			body = body[:0]
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+
+	return sections
+}
+
+// sarifResult returns the SARIF result for diag reported for rule.
+func (a *analyzer) sarifResult(rule string, diag analysis.Diagnostic) sarifResult {
+	res := sarifResult{
+		RuleID:    rule,
+		Level:     "warning",
+		Message:   sarifMessage{Text: diag.Message},
+		Locations: []sarifLocation{{PhysicalLocation: a.sarifLocation(diag.Pos, diag.End)}},
+	}
+
+	for i, rel := range diag.Related {
+		res.RelatedLocations = append(res.RelatedLocations, sarifLocation{
+			ID:               i + 1,
+			PhysicalLocation: a.sarifLocation(rel.Pos, rel.End),
+			Message:          &sarifMessage{Text: rel.Message},
+		})
+	}
+
+	for _, fix := range diag.SuggestedFixes {
+		sf := sarifFix{Description: sarifMessage{Text: fix.Message}}
+		changes := make(map[string]int)
+		for _, edit := range fix.TextEdits {
+			loc := a.sarifLocation(edit.Pos, edit.End)
+			uri := loc.ArtifactLocation.URI
+			i, ok := changes[uri]
+			if !ok {
+				i = len(sf.ArtifactChanges)
+				changes[uri] = i
+				sf.ArtifactChanges = append(sf.ArtifactChanges, sarifArtifactChange{
+					ArtifactLocation: loc.ArtifactLocation,
+				})
+			}
+
+			sf.ArtifactChanges[i].Replacements = append(sf.ArtifactChanges[i].Replacements, sarifReplacement{
+				DeletedRegion:   loc.Region,
+				InsertedContent: sarifMessage{Text: string(edit.NewText)},
+			})
+		}
+		res.Fixes = append(res.Fixes, sf)
+	}
+
+	return res
+}
+
+// sarifLocation returns the physical location of the range pos to end.
+func (a *analyzer) sarifLocation(pos, end token.Pos) sarifPhysicalLocation {
+	start := a.pass.Fset.Position(pos)
+	stop := start
+	if end.IsValid() {
+		stop = a.pass.Fset.Position(end)
+	}
+
+	return sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: fileURI(start.Filename)},
+		Region: sarifRegion{
+			StartLine:   start.Line,
+			StartColumn: start.Column,
+			EndLine:     stop.Line,
+			EndColumn:   stop.Column,
+		},
+	}
+}
+
+// fileURI returns the URI of file, relative to the working directory
+// if it's below it, otherwise absolute.
+func fileURI(file string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+
+	return "file://" + filepath.ToSlash(file)
+}
@@ -0,0 +1,77 @@
+package uncalled_test
+
+import (
+	"database/sql"
+	"os"
+)
+
+func CalledAll(db *sql.DB) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return rows.Err()
+}
+
+func NotCalledClose(db *sql.DB) error {
+	rows, err := db.Query("select id from tb") // want "rows.Close\\(\\) must be called before return at line 32"
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return rows.Err()
+}
+
+func NotCalledEither(db *sql.DB) {
+	rows, _ := db.Query("select id from tb") // want "rows.Close\\(\\) must be called before end of function at line 38" "rows.Err\\(\\) must be called before end of function at line 38"
+	_ = rows
+}
+
+func CalledFile(name string, data []byte) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(data)
+	if serr := f.Sync(); err == nil {
+		err = serr
+	}
+
+	return err
+}
+
+func NotCalledSync(name string, data []byte) error {
+	f, err := os.Create(name) // want "f.Sync\\(\\) must be called before return at line 63"
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func NotCalledOpen(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	_ = f
+
+	return nil
+}
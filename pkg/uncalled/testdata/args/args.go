@@ -0,0 +1,54 @@
+package uncalled_test
+
+import (
+	"context"
+
+	"resource"
+)
+
+func CalledRelease() {
+	h, tok, err := resource.Open()
+	if err != nil {
+		return
+	}
+	defer h.Release(tok)
+}
+
+func NotCalledReleaseOther(other *resource.Token) {
+	h, tok, err := resource.Open() // want "h.Release\\(\\$1\\) must be called before end of function at line 24"
+	if err != nil {
+		return
+	}
+	_ = tok
+	h.Release(other)
+}
+
+func CalledClose(ctx context.Context) {
+	c := resource.Dial()
+	c.Close(ctx, "done", -1, nil)
+}
+
+func NotCalledCloseType() {
+	c := resource.Dial() // want "c.Close\\(context.Context,\"done\",-1,nil\\) must be called before end of function at line 34"
+	c.Close("ctx", "done", -1, nil)
+}
+
+func NotCalledCloseValue(ctx context.Context) {
+	c := resource.Dial() // want "c.Close\\(context.Context,\"done\",-1,nil\\) must be called before end of function at line 39"
+	c.Close(ctx, "other", -1, nil)
+}
+
+func NotCalledCloseNil(ctx context.Context) {
+	c := resource.Dial() // want "c.Close\\(context.Context,\"done\",-1,nil\\) must be called before end of function at line 44"
+	c.Close(ctx, "done", -1, &resource.Options{})
+}
+
+func CalledReturn(k *resource.Key) {
+	l := resource.Acquire(k)
+	defer l.Return(k)
+}
+
+func NotCalledReturnOther(k, other *resource.Key) {
+	l := resource.Acquire(k) // want "l.Return\\(@0\\) must be called before end of function at line 54"
+	l.Return(other)
+}
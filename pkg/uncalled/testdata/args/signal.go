@@ -0,0 +1,51 @@
+package uncalled_test
+
+import (
+	"os"
+	"os/signal"
+)
+
+func CalledStop() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+	defer signal.Stop(ch)
+	<-ch
+}
+
+func CalledStopAlias() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+	other := ch
+	signal.Stop(other)
+}
+
+func CalledStopHelper() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+	stop(ch)
+}
+
+func stop(ch chan os.Signal) { // want stop:"releases\\(signal-stop\\[0\\]\\)"
+	signal.Stop(ch)
+}
+
+func NotCalledStop() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt) // want "signal.Stop\\(@0\\) must be called before end of function at line 36"
+	<-ch
+}
+
+func NotCalledStopOther(other chan os.Signal) { // want NotCalledStopOther:"releases\\(signal-stop\\[0\\]\\)"
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt) // want "signal.Stop\\(@0\\) must be called before end of function at line 42"
+	signal.Stop(other)
+}
+
+func NotCalledStopPath(done bool) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt) // want "signal.Stop\\(@0\\) must be called before return at line 48"
+	if done {
+		return
+	}
+	signal.Stop(ch)
+}
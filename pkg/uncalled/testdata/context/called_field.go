@@ -0,0 +1,43 @@
+package uncalled_test
+
+import (
+	"context"
+)
+
+type Server struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (s *Server) Stop() { // want Stop:"owns\\(context-cancel\\[cancel\\]\\)"
+	s.cancel()
+}
+
+func NewServer() *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{ctx: ctx, cancel: cancel}
+}
+
+func (s *Server) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.ctx = ctx
+	s.cancel = cancel
+}
+
+func (s *Server) Restart() {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+}
+
+type worker struct {
+	cancel context.CancelFunc
+}
+
+func (w worker) Close() error { // want Close:"owns\\(context-cancel\\[cancel\\]\\)"
+	defer w.cancel()
+	return nil
+}
+
+func newWorker() worker {
+	_, cancel := context.WithCancel(context.Background())
+	return worker{cancel}
+}
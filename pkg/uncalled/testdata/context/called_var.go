@@ -0,0 +1,37 @@
+package uncalled_test
+
+import (
+	"context"
+	"fmt"
+)
+
+var pkgCtx, pkgCancel = context.WithCancel(context.Background())
+
+func init() {
+	go func() {
+		<-pkgCtx.Done()
+	}()
+}
+
+func Shutdown() {
+	pkgCancel()
+}
+
+func CalledVar() {
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	fmt.Println(ctx)
+}
+
+func CalledVarGroup() {
+	var (
+		parent      = context.Background()
+		ctx, cancel = context.WithCancel(parent)
+	)
+	defer cancel()
+
+	fmt.Println(ctx)
+}
+
+var otherCtx, otherCancel = context.WithCancel(context.Background())
@@ -0,0 +1,10 @@
+package uncalled_test
+
+import (
+	"fmt"
+)
+
+func StopOther() {
+	fmt.Println(otherCtx)
+	otherCancel()
+}
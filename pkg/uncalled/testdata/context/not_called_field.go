@@ -0,0 +1,24 @@
+package uncalled_test
+
+import (
+	"context"
+)
+
+type Client struct {
+	cancel context.CancelFunc
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func NewClient() *Client {
+	_, cancel := context.WithCancel(context.Background())
+	return &Client{cancel: cancel} // want "Client.cancel\\(\\) must be called by a Close, Stop or Shutdown method of Client"
+}
+
+func (c *Client) Start() {
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background()) // want "Client.cancel\\(\\) must be called by a Close, Stop or Shutdown method of Client"
+	_ = ctx
+}
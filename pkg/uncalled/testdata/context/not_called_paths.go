@@ -0,0 +1,14 @@
+package uncalled_test
+
+import (
+	"context"
+)
+
+func NotCalledBranch(cond bool) {
+	ctx := context.Background()
+	_, cancel := context.WithCancel(ctx) // want "cancel\\(\\) must be called before return at line 11"
+	if cond {
+		return
+	}
+	defer cancel()
+}
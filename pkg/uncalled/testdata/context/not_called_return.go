@@ -0,0 +1,26 @@
+package uncalled_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+func newCtx(fail bool) (context.Context, context.CancelFunc, error) { // want newCtx:"acquires\\(context-cancel\\[1\\]\\)"
+	ctx, cancel := context.WithCancel(context.Background())
+	if fail {
+		return ctx, cancel, errors.New("fail")
+	}
+	return ctx, cancel, nil
+}
+
+func NotCalledNewCtx(fail bool) error {
+	ctx, cancel, err := newCtx(fail) // want "cancel\\(\\) must be called before return at line 20"
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	fmt.Println(ctx)
+	return nil
+}
@@ -0,0 +1,30 @@
+package uncalled_test
+
+import (
+	"context"
+	"fmt"
+)
+
+var leakCtx, leakCancel = context.WithCancel(context.Background()) // want "leakCancel\\(\\) must be called"
+
+func init() {
+	fmt.Println(leakCtx)
+}
+
+func NotCalledVar() {
+	var ctx, cancel = context.WithCancel(context.Background()) // want "cancel\\(\\) must be called before end of function at line 17"
+	fmt.Println(ctx, cancel != nil)
+}
+
+func NotCalledVarGroup(early bool) {
+	var (
+		parent      = context.Background()
+		ctx, cancel = context.WithCancel(parent) // want "cancel\\(\\) must be called before return at line 25"
+	)
+	if early {
+		return
+	}
+	defer cancel()
+
+	fmt.Println(ctx)
+}
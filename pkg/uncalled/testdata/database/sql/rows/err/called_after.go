@@ -0,0 +1,41 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func CalledBeforeAndAfterLoop(db *sql.DB) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return rows.Err()
+}
+
+func CalledDeferred(db *sql.DB) (err error) {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if rerr := rows.Err(); err == nil {
+			err = rerr
+		}
+	}()
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package uncalled_test
+
+import (
+	"context"
+	"database/sql"
+)
+
+func CalledCaseSwitch(db *sql.DB, all bool) error {
+	switch {
+	case all:
+		rows, err := db.Query("select id from tb")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+
+		return rows.Err()
+	default:
+		return nil
+	}
+}
+
+func CalledCaseTypeSwitch(q interface{}) error {
+	switch q := q.(type) {
+	case *sql.DB:
+		rows, err := q.Query("select id from tb")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+
+		return rows.Err()
+	case *sql.Tx:
+		return nil
+	}
+
+	return nil
+}
+
+func CalledCaseSelect(ctx context.Context, db *sql.DB, ready <-chan struct{}) error {
+	select {
+	case <-ready:
+		rows, err := db.QueryContext(ctx, "select id from tb")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+
+		return rows.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -4,9 +4,10 @@ import (
 	"database/sql"
 )
 
-func CalledDefer(db *sql.DB) {
+func CalledDefer(db *sql.DB) (err error) {
 	rows, _ := db.Query("select id from tb")
 	defer func() {
-		_ = rows.Err()
+		err = rows.Err()
 	}()
+	return nil
 }
@@ -6,8 +6,8 @@ import (
 
 func CalledFunc(db *sql.DB) {
 	rows, _ := db.Query("")
-	resCloser, n := func(rs *sql.Rows, other int) {
-		_ = rs.Err()
+	resCloser, n := func(rs *sql.Rows, other int) error {
+		return rs.Err()
 	}, 1
 	resCloser(rows, n)
 }
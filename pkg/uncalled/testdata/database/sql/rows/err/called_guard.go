@@ -0,0 +1,16 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func CalledGuard(db *sql.DB) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		// Handle row.
+	}
+	return rows.Err()
+}
@@ -0,0 +1,40 @@
+package uncalled_test
+
+import (
+	"database/sql"
+	"helpers"
+)
+
+func consume(rows *sql.Rows) error { // want consume:"releases\\(sql-rows-err\\[0\\]\\)"
+	return helpers.Consume(rows)
+}
+
+func CalledHelper(db *sql.DB) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+	return helpers.Consume(rows)
+}
+
+func CalledLocalHelper(db *sql.DB) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+	return consume(rows)
+}
+
+func CalledMethodHelper(db *sql.DB, h *helpers.Handler) {
+	rows, _ := db.Query("select id from tb")
+	h.Handle(1, rows)
+}
+
+func CalledOwnerHelper(db *sql.DB, h *helpers.Holder) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+	h.Rows = rows
+	return nil
+}
@@ -0,0 +1,102 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func CalledInitIf(db *sql.DB) error {
+	if rows, err := db.Query("select id from tb"); err == nil {
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+
+		return rows.Err()
+	}
+
+	return nil
+}
+
+func CalledInitIfElse(db *sql.DB) error {
+	if rows, err := db.Query("select id from tb"); err != nil {
+		return err
+	} else {
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+
+		return rows.Err()
+	}
+}
+
+func CalledInitElseIf(db *sql.DB, skip bool) error {
+	if skip {
+		return nil
+	} else if rows, err := db.Query("select id from tb"); err == nil {
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+
+		return rows.Err()
+	}
+
+	return nil
+}
+
+func CalledInitSwitch(db *sql.DB) error {
+	switch rows, err := db.Query("select id from tb"); {
+	case err != nil:
+		return err
+	default:
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+
+		return rows.Err()
+	}
+}
+
+func CalledInitTypeSwitch(db *sql.DB, v interface{}) error {
+	switch rows, err := db.Query("select id from tb"); v.(type) {
+	case string:
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+
+		return rows.Err()
+	default:
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		return rows.Err()
+	}
+}
+
+func CalledInitFor(db *sql.DB) error {
+	for rows, err := db.Query("select id from tb"); ; {
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+
+		return rows.Err()
+	}
+}
@@ -5,8 +5,8 @@ import (
 )
 
 func CalledInlineFunc(db *sql.DB) {
-	_ = func(db *sql.DB) {
+	_ = func(db *sql.DB) error {
 		rows, _ := db.Query("") // OK
-		_ = rows.Err()
+		return rows.Err()
 	}
 }
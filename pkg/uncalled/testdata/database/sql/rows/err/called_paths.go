@@ -0,0 +1,34 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func CalledBranches(db *sql.DB, cond bool) error {
+	rows, _ := db.Query("select id from tb")
+	for rows.Next() {
+		// Handle row.
+	}
+	if cond {
+		return rows.Err()
+	}
+	return rows.Err()
+}
+
+func CalledPanic(db *sql.DB, cond bool) error {
+	rows, _ := db.Query("select id from tb")
+	if cond {
+		panic("failed")
+	}
+	return rows.Err()
+}
+
+func CalledSwitch(db *sql.DB, n int) error {
+	rows, _ := db.Query("select id from tb")
+	switch n {
+	case 1:
+		return rows.Err()
+	default:
+		return rows.Err()
+	}
+}
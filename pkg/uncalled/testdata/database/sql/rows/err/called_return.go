@@ -0,0 +1,53 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func open(db *sql.DB) (*sql.Rows, error) { // want open:"acquires\\(sql-rows-err\\[0\\]\\)"
+	return db.Query("select id from tb")
+}
+
+func openAssign(db *sql.DB) (*sql.Rows, error) { // want openAssign:"acquires\\(sql-rows-err\\[0\\]\\)"
+	rows, err := db.Query("select id from tb")
+	return rows, err
+}
+
+func openNamed(db *sql.DB) (rows *sql.Rows, err error) { // want openNamed:"acquires\\(sql-rows-err\\[0\\]\\)"
+	rows, err = db.Query("select id from tb")
+	return
+}
+
+func openRows(db *sql.DB) *sql.Rows { // want openRows:"acquires\\(sql-rows-err\\[0\\]\\)"
+	rows, _ := db.Query("select id from tb")
+	return rows
+}
+
+func openCount(db *sql.DB) (int, *sql.Rows, error) { // want openCount:"acquires\\(sql-rows-err\\[1\\]\\)"
+	rows, err := openAssign(db)
+	if err != nil {
+		return 0, nil, err
+	}
+	return 1, rows, nil
+}
+
+func CalledOpen(db *sql.DB) error {
+	rows, err := open(db)
+	if err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+func CalledOpenRows(db *sql.DB) error {
+	rows := openRows(db)
+	return rows.Err()
+}
+
+func CalledOpenCount(db *sql.DB) error {
+	_, rows, err := openCount(db)
+	if err != nil {
+		return err
+	}
+	return rows.Err()
+}
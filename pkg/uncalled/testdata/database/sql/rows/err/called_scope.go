@@ -0,0 +1,49 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func CalledScopeIfElse(db *sql.DB, all bool) error {
+	var rows *sql.Rows
+	var err error
+	if all {
+		rows, err = db.Query("select id from tb")
+	} else {
+		rows, err = db.Query("select id from tb where active")
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return rows.Err()
+}
+
+func CalledScopeNested(db *sql.DB, all, active bool) error {
+	var rows *sql.Rows
+	var err error
+	if all {
+		if active {
+			rows, err = db.Query("select id from tb where active")
+		} else {
+			rows, err = db.Query("select id from tb")
+		}
+	} else {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,47 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func CalledVar(db *sql.DB) error {
+	var rows, err = db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return rows.Err()
+}
+
+func CalledVarCopy(db *sql.DB) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var r = rows
+	for r.Next() {
+		// Handle row.
+	}
+
+	return r.Err()
+}
+
+func CalledVarUninitialised(db *sql.DB) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+	var _ int
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return rows.Err()
+}
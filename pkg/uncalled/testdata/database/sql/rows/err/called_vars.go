@@ -15,18 +15,18 @@ func CalledVars(db *sql.DB) {
 
 	rows1 := rows
 	rows2 := rows1
-	_ = rows2.Err()
+	_ = rows2.Err() // want "rows2.Err\\(\\) called but result ignored"
 
 	rows3, err := db.Query("") // OK
 	rowsX3 := rows3
-	_ = rowsX3.Err()
+	fmt.Fprint(io.Discard, rowsX3.Err())
 	if err != nil {
 		// handle error
 		fmt.Fprint(io.Discard, err)
 	}
 
 	rows3, err = db.Query("") // want "rows3.Err\\(\\) must be called"
-	_ = rowsX3.Err()
+	fmt.Fprint(io.Discard, rowsX3.Err())
 	if err != nil {
 		// handle error
 		fmt.Fprint(io.Discard, err)
@@ -0,0 +1,14 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func NotCalledBlank(db *sql.DB) error {
+	rows, err := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before return at line 13"
+	if err != nil {
+		return err
+	}
+	_ = rows
+	return nil
+}
@@ -0,0 +1,59 @@
+package uncalled_test
+
+import (
+	"context"
+	"database/sql"
+)
+
+func NotCalledCaseSwitch(db *sql.DB, all bool) error {
+	switch {
+	case all:
+		rows, err := db.Query("select id from tb") // want "rows.Err\\(\\) must be called"
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+	}
+
+	return nil
+}
+
+func NotCalledCaseTypeSwitch(q interface{}) error {
+	switch q := q.(type) {
+	case *sql.DB:
+		rows, err := q.Query("select id from tb") // want "rows.Err\\(\\) must be called"
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+	}
+
+	return nil
+}
+
+func NotCalledCaseSelect(ctx context.Context, db *sql.DB, ready <-chan struct{}) error {
+	select {
+	case <-ready:
+		rows, err := db.QueryContext(ctx, "select id from tb") // want "rows.Err\\(\\) must be called"
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func NotCalledBeforeLoop(db *sql.DB) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+
+	if err := rows.Err(); err != nil { // want "rows.Err\\(\\) called before iteration finished"
+		return err
+	}
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return nil
+}
+
+func NotCalledInLoop(db *sql.DB) error {
+	rows, err := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before return at line 36"
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		if err := rows.Err(); err != nil { // want "rows.Err\\(\\) called before iteration finished"
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package uncalled_test
+
+import (
+	"database/sql"
+	"helpers"
+)
+
+func NotCalledHelper(db *sql.DB) {
+	rows, _ := db.Query("select id from tb") // want "rows.Err\\(\\) must be called"
+	helpers.Store(true, rows)
+}
+
+func NotCalledOwnerHelper(db *sql.DB, h *helpers.Holder) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+	h.Pending = rows // want "Holder.Pending.Err\\(\\) must be called by a Close, Stop or Shutdown method of Holder"
+	return nil
+}
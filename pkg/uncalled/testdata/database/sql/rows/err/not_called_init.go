@@ -0,0 +1,92 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func NotCalledInitIf(db *sql.DB) error {
+	if rows, err := db.Query("select id from tb"); err == nil { // want "rows.Err\\(\\) must be called before return at line 16"
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+	}
+
+	return nil
+}
+
+func NotCalledInitIfElse(db *sql.DB) error {
+	if rows, err := db.Query("select id from tb"); err != nil { // want "rows.Err\\(\\) must be called before return at line 30"
+		return err
+	} else {
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+	}
+
+	return nil
+}
+
+func NotCalledInitElseIf(db *sql.DB, skip bool) error {
+	if skip {
+		return nil
+	} else if rows, err := db.Query("select id from tb"); err == nil { // want "rows.Err\\(\\) must be called before return at line 44"
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+	}
+
+	return nil
+}
+
+func NotCalledInitSwitch(db *sql.DB) error {
+	switch rows, err := db.Query("select id from tb"); { // want "rows.Err\\(\\) must be called before return at line 58"
+	case err != nil:
+		return err
+	default:
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+
+		return nil
+	}
+}
+
+func NotCalledInitTypeSwitch(db *sql.DB, v interface{}) error {
+	switch rows, err := db.Query("select id from tb"); v.(type) { // want "rows.Err\\(\\) must be called before return at line 72"
+	case string:
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		return rows.Err()
+	default:
+		return nil
+	}
+}
+
+func NotCalledInitShadowed(db *sql.DB) error {
+	if rows, err := db.Query("select id from tb"); err == nil { // want "rows.Err\\(\\) must be called before return at line 87"
+		defer rows.Close()
+
+		for rows.Next() {
+			// Handle row.
+		}
+	}
+
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return rows.Err()
+}
@@ -0,0 +1,38 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func NotCalledBranch(db *sql.DB, cond bool) error {
+	rows, _ := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before return at line 16"
+	for rows.Next() {
+		// Handle row.
+	}
+	if cond {
+		return rows.Err()
+	}
+	// Handle rows.
+	return nil
+}
+
+func NotCalledEarlyReturn(db *sql.DB, cond bool) error {
+	rows, _ := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before return at line 25"
+	for rows.Next() {
+		// Handle row.
+	}
+	if cond {
+		return nil
+	}
+	return rows.Err()
+}
+
+func NotCalledLoopBreak(db *sql.DB, ids []int) error {
+	rows, _ := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before return at line 34"
+	for _, id := range ids {
+		if id == 0 {
+			return nil
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,30 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func NotCalledOpen(db *sql.DB) {
+	rows, _ := openNamed(db) // want "rows.Err\\(\\) must be called"
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func NotCalledOpenRows(db *sql.DB) {
+	rows := openRows(db) // want "rows.Err\\(\\) must be called"
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func NotCalledOpenCount(db *sql.DB) error {
+	_, rows, err := openCount(db) // want "rows.Err\\(\\) must be called before return at line 29"
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		// Handle row.
+	}
+	return nil
+}
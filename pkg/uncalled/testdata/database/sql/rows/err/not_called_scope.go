@@ -0,0 +1,25 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func NotCalledScopeIfElse(db *sql.DB, all bool) error {
+	var rows *sql.Rows
+	var err error
+	if all {
+		rows, err = db.Query("select id from tb") // want "rows.Err\\(\\) must be called before return at line 24"
+	} else {
+		rows, err = db.Query("select id from tb where active") // want "rows.Err\\(\\) must be called before return at line 24"
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return nil
+}
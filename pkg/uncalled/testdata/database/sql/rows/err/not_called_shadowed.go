@@ -0,0 +1,67 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func NotCalledShadowedBlock(db *sql.DB) error {
+	rows, err := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before return at line 21"
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	{
+		rows, err := db.Query("select name from tb")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func NotCalledShadowedFuncLit(db *sql.DB, other *sql.Rows) error {
+	rows, err := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before return at line 49"
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	check := func() error {
+		rows := other
+		return rows.Err()
+	}
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return check()
+}
+
+type holder struct {
+	rows *sql.Rows
+}
+
+func NotCalledShadowedKey(db *sql.DB) error {
+	rows, err := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before return at line 66"
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	h := holder{rows: nil}
+	_ = h
+
+	return nil
+}
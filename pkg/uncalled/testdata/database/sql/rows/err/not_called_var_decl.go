@@ -0,0 +1,19 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func NotCalledVarDecl(db *sql.DB) error {
+	var rows, err = db.Query("select id from tb") // want "rows.Err\\(\\) must be called before return at line 18"
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return nil
+}
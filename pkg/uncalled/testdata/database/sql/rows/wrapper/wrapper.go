@@ -0,0 +1,33 @@
+package uncalled_test
+
+import (
+	"repo"
+)
+
+func CalledWrapper(r *repo.Repo) error {
+	rows, err := r.List()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return rows.Err()
+}
+
+func NotCalledWrapper(r *repo.Repo) error {
+	rows, err := r.List() // want "rows.Err\\(\\) must be called"
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package uncalled_test
+
+import (
+	"context"
+	"database/sql"
+)
+
+func CalledCommit(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("delete from tb"); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func CalledDeferRollback(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "delete from tb"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func CalledConn(ctx context.Context, conn *sql.Conn) error {
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	return tx.QueryRowContext(ctx, "select id from tb").Scan(new(int))
+}
+
+func CalledEither(db *sql.DB, commit bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if commit {
+		return tx.Commit()
+	}
+
+	return tx.Rollback()
+}
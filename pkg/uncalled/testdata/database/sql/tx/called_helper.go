@@ -0,0 +1,25 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func finish(tx *sql.Tx, err error) error { // want finish:"releases\\(sql-tx-finish\\[0\\]\\)"
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func CalledHelper(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("delete from tb")
+
+	return finish(tx, err)
+}
@@ -0,0 +1,23 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+func NotCalled(db *sql.DB) {
+	tx, _ := db.Begin() // want "tx.Commit\\(\\) or tx.Rollback\\(\\) must be called before end of function at line 10"
+	_, _ = tx.Exec("delete from tb")
+}
+
+func NotCalledError(db *sql.DB) error {
+	tx, err := db.Begin() // want "tx.Commit\\(\\) or tx.Rollback\\(\\) must be called before return at line 19"
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("delete from tb"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
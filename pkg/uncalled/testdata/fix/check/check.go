@@ -0,0 +1,76 @@
+package uncalled_test
+
+import (
+	"database/sql"
+)
+
+type item struct {
+	id int
+}
+
+func List(db *sql.DB) ([]item, error) {
+	rows, err := db.Query("select id from tb") // want "rows.Err\\(\\) must be called"
+	if err != nil {
+		return nil, err
+	}
+	var items []item
+	for rows.Next() {
+		var i item
+		if err := rows.Scan(&i.id); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, nil
+}
+
+func First(db *sql.DB) (item, string, bool, error) {
+	rows, err := db.Query("select id from tb") // want "rows.Err\\(\\) must be called"
+	if err != nil {
+		return item{}, "", false, err
+	}
+	return item{}, "", rows.Next(), nil
+}
+
+func Print(db *sql.DB) {
+	rows, _ := db.Query("select id from tb") // want "rows.Err\\(\\) must be called"
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func ListScope(db *sql.DB, all bool) ([]item, error) {
+	var rows *sql.Rows
+	var err error
+	if all {
+		rows, err = db.Query("select id from tb") // want "rows.Err\\(\\) must be called"
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, nil
+	}
+	var items []item
+	for rows.Next() {
+		var i item
+		if err := rows.Scan(&i.id); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, nil
+}
+
+func Single(db *sql.DB) (item, error) {
+	rows, err := db.Query("select id from tb") // want "rows.Err\\(\\) must be called"
+	if err != nil {
+		return item{}, err
+	}
+	var i item
+	if rows.Next() {
+		if err := rows.Scan(&i.id); err != nil {
+			return item{}, err
+		}
+	}
+	return i, nil
+}
@@ -0,0 +1,56 @@
+package uncalled_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+)
+
+func Cancel() {
+	ctx, cancel := context.WithCancel(context.Background()) // want "cancel\\(\\) must be called"
+	fmt.Println(ctx, cancel != nil)
+}
+
+func Body() error {
+	resp, err := http.Get("http://example.com/") // want "resp.Body.Close\\(\\) must be called"
+	if err != nil {
+		return err
+	}
+	fmt.Println(resp.Status)
+	return nil
+}
+
+func BodyUnchecked() {
+	resp, _ := http.Get("http://example.com/") // want "resp.Body.Close\\(\\) must be called"
+	fmt.Println(resp.Status)
+}
+
+func Tx(db *sql.DB) error {
+	tx, err := db.Begin() // want "tx.Commit\\(\\) or tx.Rollback\\(\\) must be called"
+	if err != nil {
+		return err
+	}
+	fmt.Println(tx != nil)
+	return nil
+}
+
+func CancelVar() {
+	var ctx, cancel = context.WithCancel(context.Background()) // want "cancel\\(\\) must be called"
+	fmt.Println(ctx, cancel != nil)
+}
+
+func TxScope(db *sql.DB, ro bool) error {
+	var tx *sql.Tx
+	var err error
+	if ro {
+		tx, err = db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true}) // want "tx.Commit\\(\\) or tx.Rollback\\(\\) must be called"
+	} else {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(tx != nil)
+	return nil
+}
@@ -0,0 +1,23 @@
+//uncalled:ignore http-response-body-close bodies are drained by the transport
+
+package uncalled_test
+
+import (
+	"net/http"
+)
+
+func IgnoreFile() {
+	resp, err := http.Get("http://example.com")
+	if err != nil {
+		return
+	}
+	_ = resp
+}
+
+func IgnoreFileOther() {
+	resp, err := http.Get("http://example.com")
+	if err != nil {
+		return
+	}
+	_ = resp
+}
@@ -0,0 +1,42 @@
+package uncalled_test
+
+import (
+	"context"
+	"database/sql"
+)
+
+// IgnoreFunc doesn't check rows.Err.
+//
+//uncalled:ignore sql-rows-err rows are discarded
+func IgnoreFunc(db *sql.DB, ctx context.Context) {
+	rows, _ := db.Query("select id from tb")
+	for rows.Next() {
+		// Handle row.
+	}
+
+	rows2, _ := db.Query("select id from tb")
+	for rows2.Next() {
+		// Handle row.
+	}
+
+	_, cancel := context.WithCancel(ctx) // want "cancel\\(\\) must be called before end of function at line 24"
+	_ = cancel
+}
+
+// IgnoreFuncUnused checks rows.Err.
+//
+//uncalled:ignore sql-rows-err no longer needed // want "directive for sql-rows-err does not suppress anything"
+func IgnoreFuncUnused(db *sql.DB) error {
+	rows, _ := db.Query("select id from tb")
+	for rows.Next() {
+		// Handle row.
+	}
+	return rows.Err()
+}
+
+func IgnoreFuncOnly(db *sql.DB) {
+	rows, _ := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before end of function at line 42"
+	for rows.Next() {
+		// Handle row.
+	}
+}
@@ -0,0 +1,67 @@
+package uncalled_test
+
+import (
+	"context"
+	"database/sql"
+)
+
+func IgnoreLine(db *sql.DB) {
+	rows, _ := db.Query("select id from tb") //uncalled:ignore sql-rows-err rows are discarded
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func IgnoreLineAbove(db *sql.DB) {
+	//uncalled:ignore sql-rows-err directive above doesn't apply // want "directive for sql-rows-err does not suppress anything"
+	rows, _ := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before end of function at line 21"
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func IgnoreLineMultiple(db *sql.DB) {
+	rows, _ := db.Query("select id from tb") //uncalled:ignore sql-rows-err,context-cancel only one used // want "directive for context-cancel does not suppress anything"
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func IgnoreLineOtherRule(db *sql.DB) {
+	rows, _ := db.Query("select id from tb") //uncalled:ignore context-cancel wrong rule // want "rows.Err\\(\\) must be called before end of function at line 35" "directive for context-cancel does not suppress anything"
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func IgnoreNolint(ctx context.Context) {
+	_, cancel := context.WithCancel(ctx) //nolint:uncalled // Cancelled by the caller.
+	_ = cancel
+}
+
+func IgnoreNolintUnused(ctx context.Context) {
+	_, cancel := context.WithCancel(ctx) //nolint:errcheck,uncalled // want "directive for uncalled does not suppress anything"
+	defer cancel()
+}
+
+func IgnoreNolintAll(ctx context.Context) {
+	_, cancel := context.WithCancel(ctx) //nolint
+	_ = cancel
+}
+
+func IgnoreNolintAllUnused(ctx context.Context) {
+	_, cancel := context.WithCancel(ctx) //nolint
+	defer cancel()
+}
+
+func IgnoreNolintOther(ctx context.Context) {
+	_, cancel := context.WithCancel(ctx) //nolint:errcheck // want "cancel\\(\\) must be called before end of function at line 60"
+	_ = cancel
+}
+
+func IgnoreMalformed(db *sql.DB) {
+	rows, _ := db.Query("select id from tb") //uncalled:ignore sql-rows-err // want "uncalled:ignore directive must specify rules and a reason" "rows.Err\\(\\) must be called before end of function at line 67"
+	for rows.Next() {
+		// Handle row.
+	}
+}
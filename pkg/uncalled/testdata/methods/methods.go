@@ -0,0 +1,38 @@
+package uncalled_test
+
+import (
+	"context"
+	"database/sql"
+)
+
+func NotCalledMethod(db *sql.DB) {
+	rows, _ := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before end of function at line 13"
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func NotCalledMethodConn(ctx context.Context, conn *sql.Conn) {
+	rows, _ := conn.QueryContext(ctx, "select id from tb") // want "rows.Err\\(\\) must be called before end of function at line 20"
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func NotCalledUnlisted(ctx context.Context, db *sql.DB) {
+	rows, _ := db.QueryContext(ctx, "select id from tb")
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func NotCalledOther() {
+	rows, _ := query()
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func query() (*sql.Rows, error) {
+	return nil, nil
+}
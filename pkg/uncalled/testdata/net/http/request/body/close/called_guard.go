@@ -0,0 +1,39 @@
+package uncalled_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+func CalledGuard() error {
+	resp, err := http.Get("http://example.com/")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(body))
+
+	return nil
+}
+
+func CalledGuardEqual() {
+	resp, err := http.Get("http://example.com/")
+	if err == nil {
+		defer resp.Body.Close()
+	}
+}
+
+func CalledGuardSwitch() error {
+	resp, err := http.Get("http://example.com/")
+	switch {
+	case err != nil:
+		return err
+	}
+	return resp.Body.Close()
+}
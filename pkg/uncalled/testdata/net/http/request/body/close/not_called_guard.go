@@ -0,0 +1,43 @@
+package uncalled_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+func NotCalledGuardReassigned() error {
+	resp, err := http.Get("http://example.com/") // want "resp.Body.Close\\(\\) must be called before return at line 17"
+	if err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	fmt.Println(string(body))
+
+	return nil
+}
+
+func NotCalledUnchecked(cond bool) error {
+	resp, err := http.Get("http://example.com/") // want "resp.Body.Close\\(\\) must be called before return at line 28"
+	if cond {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func NotCalledGuardEqual() error {
+	resp, err := http.Get("http://example.com/") // want "resp.Body.Close\\(\\) must be called before return at line 38"
+	if err == nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	return err
+}
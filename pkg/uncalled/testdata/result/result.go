@@ -0,0 +1,102 @@
+package uncalled_test
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func CalledReturned(db *sql.DB) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	return rows.Err()
+}
+
+func CalledCompared(db *sql.DB) {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return
+	}
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	if rows.Err() != nil {
+		panic("rows failed")
+	}
+}
+
+func CalledAssigned(db *sql.DB) error {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func CalledPassed(db *sql.DB) {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return
+	}
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	fmt.Println(rows.Err())
+}
+
+func NotCheckedBlank(db *sql.DB) {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return
+	}
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	_ = rows.Err() // want "rows.Err\\(\\) called but result ignored"
+}
+
+func NotCheckedStmt(db *sql.DB) {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return
+	}
+
+	for rows.Next() {
+		// Handle row.
+	}
+
+	rows.Err() // want "rows.Err\\(\\) called but result ignored"
+}
+
+func NotCheckedDefer(db *sql.DB) {
+	rows, err := db.Query("select id from tb")
+	if err != nil {
+		return
+	}
+	defer rows.Err() // want "rows.Err\\(\\) called but result ignored"
+
+	for rows.Next() {
+		// Handle row.
+	}
+}
@@ -0,0 +1,34 @@
+package baseline
+
+import (
+	"database/sql"
+)
+
+func Known(db *sql.DB) {
+	rows, _ := db.Query("select id from tb")
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func KnownOnce(db *sql.DB, cond bool) {
+	if cond {
+		rows, _ := db.Query("select id from tb")
+		for rows.Next() {
+			// Handle row.
+		}
+		return
+	}
+
+	rows, _ := db.Query("select id from tb") // want "rows.Err\\(\\) must be called before end of function at line 27"
+	for rows.Next() {
+		// Handle row.
+	}
+}
+
+func New(db *sql.DB) {
+	rows, _ := db.Query("select name from tb") // want "rows.Err\\(\\) must be called before end of function at line 34"
+	for rows.Next() {
+		// Handle row.
+	}
+}
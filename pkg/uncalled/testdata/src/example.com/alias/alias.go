@@ -0,0 +1,25 @@
+// Package alias provides an alias of a connection from another package.
+package alias
+
+import (
+	"example.com/store"
+)
+
+// Conn is an alias of store.Conn.
+type Conn = store.Conn
+
+// Session is an alias of store.Session.
+type Session = store.Session
+
+// Reason is an alias of store.Reason.
+type Reason = store.Reason
+
+// Dial returns a new connection.
+func Dial() (*Conn, error) {
+	return store.Dial()
+}
+
+// Start returns a new session.
+func Start() *Session {
+	return store.Start()
+}
@@ -0,0 +1,15 @@
+// Package generic provides generic rows for use by other test packages.
+package generic
+
+// Rows are rows of T.
+type Rows[T any] struct{}
+
+// Query returns rows of T.
+func Query[T any]() (*Rows[T], error) {
+	return &Rows[T]{}, nil
+}
+
+// Close closes the rows.
+func (r *Rows[T]) Close() error {
+	return nil
+}
@@ -0,0 +1,15 @@
+// Package major provides a connection from a major version module path.
+package major
+
+// Conn is a connection.
+type Conn struct{}
+
+// Dial returns a new connection.
+func Dial() (*Conn, error) {
+	return &Conn{}, nil
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return nil
+}
@@ -0,0 +1,29 @@
+// Package store provides a connection for use by other test packages.
+package store
+
+// Conn is a connection.
+type Conn struct{}
+
+// Dial returns a new connection.
+func Dial() (*Conn, error) {
+	return &Conn{}, nil
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return nil
+}
+
+// Reason is why a session ended.
+type Reason struct{}
+
+// Session is a session which must be ended with a Reason.
+type Session struct{}
+
+// Start returns a new session.
+func Start() *Session {
+	return &Session{}
+}
+
+// End ends the session for reason r.
+func (s *Session) End(r *Reason) {}
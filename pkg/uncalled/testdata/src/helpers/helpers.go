@@ -0,0 +1,51 @@
+// Package helpers provides functions which release resources passed
+// to them for use by other test packages.
+package helpers
+
+import (
+	"database/sql"
+)
+
+// Consume processes all rows returning any error.
+func Consume(rows *sql.Rows) error {
+	for rows.Next() {
+		// Handle row.
+	}
+	return rows.Err()
+}
+
+// Store stores rows, which it releases if the store is valid.
+func Store(valid bool, rows *sql.Rows) error {
+	if valid {
+		return rows.Err()
+	}
+	return nil
+}
+
+// Handler handles rows.
+type Handler struct{}
+
+// Handle processes all rows.
+func (h *Handler) Handle(_ int, rows *sql.Rows) (err error) {
+	defer func() {
+		err = rows.Err()
+	}()
+	for rows.Next() {
+		// Handle row.
+	}
+	return nil
+}
+
+// Holder holds rows for other packages.
+type Holder struct {
+	// Rows is released by Close.
+	Rows *sql.Rows
+
+	// Pending is not released.
+	Pending *sql.Rows
+}
+
+// Close releases the rows held.
+func (h *Holder) Close() error {
+	return h.Rows.Err()
+}
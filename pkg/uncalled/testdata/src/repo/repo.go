@@ -0,0 +1,17 @@
+// Package repo wraps database/sql for use by other test packages, which
+// don't import database/sql themselves.
+package repo
+
+import (
+	"database/sql"
+)
+
+// Repo is a repository.
+type Repo struct {
+	db *sql.DB
+}
+
+// List returns the rows of the repository.
+func (r *Repo) List() (*sql.Rows, error) {
+	return r.db.Query("select id from tb")
+}
@@ -0,0 +1,46 @@
+// Package resource provides resources whose release requires specific
+// arguments for use by other test packages.
+package resource
+
+// Handle is a resource which must be released with its Token.
+type Handle struct{}
+
+// Token identifies the acquisition of a Handle.
+type Token struct{}
+
+// Open returns a new Handle and the Token required to release it.
+func Open() (*Handle, *Token, error) {
+	return &Handle{}, &Token{}, nil
+}
+
+// Release releases h acquired with t.
+func (h *Handle) Release(t *Token) {}
+
+// Conn is a resource which must be closed with a reason.
+type Conn struct{}
+
+// Options configures how a Conn is closed.
+type Options struct{}
+
+// Dial returns a new Conn.
+func Dial() *Conn {
+	return &Conn{}
+}
+
+// Close closes c.
+func (c *Conn) Close(ctx interface{}, reason string, code int, opts *Options) {}
+
+// Key identifies a Lease.
+type Key struct{}
+
+// Lease is a resource which must be returned with the Key it was
+// acquired with.
+type Lease struct{}
+
+// Acquire returns a new Lease for k.
+func Acquire(k *Key) *Lease {
+	return &Lease{}
+}
+
+// Return returns l acquired with k.
+func (l *Lease) Return(k *Key) {}
@@ -0,0 +1,45 @@
+package alias
+
+import (
+	"example.com/alias"
+	"example.com/store"
+)
+
+func Called() error {
+	conn, err := alias.Dial()
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func NotCalled() error {
+	conn, err := alias.Dial() // want "conn.Close\\(\\) must be called"
+	if err != nil {
+		return err
+	}
+	_ = conn
+
+	return nil
+}
+
+func NotCalledAliased() error {
+	conn, err := store.Dial() // want "conn.Close\\(\\) must be called"
+	if err != nil {
+		return err
+	}
+	_ = conn
+
+	return nil
+}
+
+func CalledEnd() {
+	s := alias.Start()
+	defer s.End(&store.Reason{})
+}
+
+func NotCalledEndNil() {
+	s := alias.Start() // want "s.End\\(\\*example.com/alias.Reason\\) must be called"
+	defer s.End(nil)
+}
@@ -0,0 +1,24 @@
+package generic
+
+import (
+	"example.com/generic"
+)
+
+func Called() error {
+	rows, err := generic.Query[int]()
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+func NotCalled() error {
+	rows, err := generic.Query[string]() // want "rows.Close\\(\\) must be called"
+	if err != nil {
+		return err
+	}
+	_ = rows
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package major
+
+import (
+	"example.com/major/v2"
+)
+
+func Called() error {
+	conn, err := major.Dial()
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func NotCalled() error {
+	conn, err := major.Dial() // want "conn.Close\\(\\) must be called"
+	if err != nil {
+		return err
+	}
+	_ = conn
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package vendored
+
+import (
+	"example.com/vendored"
+)
+
+func Called() error {
+	conn, err := vendored.Dial()
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func NotCalled() error {
+	conn, err := vendored.Dial() // want "conn.Close\\(\\) must be called"
+	if err != nil {
+		return err
+	}
+	_ = conn
+
+	return nil
+}
+
+func CalledEnd() {
+	s := vendored.Start()
+	defer s.End(&vendored.Reason{})
+}
+
+func NotCalledEndNil() {
+	s := vendored.Start() // want "s.End\\(\\*example.com/vendored.Reason\\) must be called"
+	defer s.End(nil)
+}
@@ -0,0 +1,69 @@
+package uncalled_test
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"net"
+	"os"
+)
+
+func CalledFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return nil
+}
+
+func NotCalledFile(name string) error {
+	f, err := os.Open(name) // want "f.Close\\(\\) must be called"
+	if err != nil {
+		return err
+	}
+	fmt.Println(f.Name())
+
+	return nil
+}
+
+func NotCalledZip(zf *zip.File) error {
+	r, err := zf.Open() // want "r.Close\\(\\) must be called"
+	if err != nil {
+		return err
+	}
+	fmt.Println(r != nil)
+
+	return nil
+}
+
+func NotCalledConn(addr string) error {
+	conn, err := net.Dial("tcp", addr) // want "conn.Close\\(\\) must be called"
+	if err != nil {
+		return err
+	}
+	fmt.Println(conn.LocalAddr())
+
+	return nil
+}
+
+func setup() (int, func()) {
+	return 1, func() {}
+}
+
+func CalledCleanup() {
+	v, cleanup := setup()
+	defer cleanup()
+	fmt.Println(v)
+}
+
+func NotCalledCleanup() {
+	v, cleanup := setup() // want "cleanup\\(\\) must be called"
+	fmt.Println(v, cleanup != nil)
+}
+
+func NotCalledCancel() {
+	ctx, cancel := context.WithCancel(context.Background()) // want "cancel\\(\\) must be called"
+	fmt.Println(ctx, cancel != nil)
+}
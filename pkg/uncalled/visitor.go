@@ -2,11 +2,15 @@ package uncalled
 
 import (
 	"go/ast"
+	"go/constant"
 	"go/token"
+	"go/types"
 	"strings"
 
 	"github.com/rs/zerolog"
 	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
 )
 
 // visitor is an ast.Vistor which searches for a call to Rows.Err()
@@ -14,12 +18,12 @@ import (
 type visitor struct {
 	pass *analysis.Pass
 
-	// identObjs contains ident.Obj entries which match the interested ident.
-	identObjs map[*ast.Object]struct{}
+	// identObjs contains the objects which match the interested ident.
+	identObjs map[types.Object]struct{}
 
 	// calledArgs maps literal function object to argument positions that
 	// resulted in a successful rule calls.
-	calledArgs map[*ast.Object]map[int]struct{}
+	calledArgs map[types.Object]map[int]struct{}
 
 	// found is set to true if we found a call to our interested
 	// ident.Err().
@@ -28,23 +32,77 @@ type visitor struct {
 	// rule contains the rule to check against.
 	rule Rule
 
+	// facts provides the parameters functions release.
+	facts *facts
+
+	// transfer is true if returning an interested ident, or storing it in
+	// a field, transfers the obligation to the caller or field owner.
+	transfer bool
+
+	// stores contains the fields interested idents were stored in.
+	stores []store
+
+	// field if set is the field of the interested idents to check.
+	field string
+
+	// results contains the named results of the function, which
+	// are returned by a bare return.
+	results []*ast.Ident
+
+	// refs maps the results of the triggering call to the objects they
+	// were assigned to, for matching $N arguments.
+	refs map[int]types.Object
+
+	// args maps the arguments of the triggering call to the objects
+	// passed, for matching @N arguments.
+	args map[int]types.Object
+
+	// call is the expected call found, nil if found by other means.
+	call *ast.CallExpr
+
+	// expect is the expectation call matched.
+	expect *Expect
+
+	// ignored is true if call was found but doesn't count as its result
+	// must be checked and was discarded.
+	ignored bool
+
+	// discarded contains the calls whose results are discarded.
+	discarded map[*ast.CallExpr]struct{}
+
 	// log is the logger to use for debugging.
 	log zerolog.Logger
 }
 
-// visit returns true if ident.Err() is called, false otherwise.
-func visit(pass *analysis.Pass, log zerolog.Logger, rule Rule, ident *ast.Ident, stmts []ast.Stmt) bool {
-	log.Debug().Stringer("ident", ident).Msg("visit")
+// newVisitor returns a new visitor which checks for rule calls on ident.
+func newVisitor(pass *analysis.Pass, log zerolog.Logger, facts *facts, rule Rule, ident *ast.Ident) *visitor {
 	ec := &visitor{
-		pass: pass,
-		identObjs: map[*ast.Object]struct{}{
-			ident.Obj: {},
-		},
-		calledArgs: make(map[*ast.Object]map[int]struct{}),
+		pass:       pass,
+		identObjs:  make(map[types.Object]struct{}),
+		calledArgs: make(map[types.Object]map[int]struct{}),
+		discarded:  make(map[*ast.CallExpr]struct{}),
 		rule:       rule,
+		facts:      facts,
 		log:        log,
 	}
 
+	if obj := ec.object(ident); obj != nil {
+		ec.identObjs[obj] = struct{}{}
+	}
+
+	return ec
+}
+
+// object returns the object ident defines or uses, nil if there is none
+// such as for the blank identifier in an assignment.
+func (ec *visitor) object(ident *ast.Ident) types.Object {
+	return ec.pass.TypesInfo.ObjectOf(ident)
+}
+
+// visit returns true if ident.Err() is called, false otherwise.
+func visit(pass *analysis.Pass, log zerolog.Logger, facts *facts, rule Rule, ident *ast.Ident, stmts []ast.Stmt) bool {
+	log.Debug().Stringer("ident", ident).Msg("visit")
+	ec := newVisitor(pass, log, facts, rule, ident)
 	for _, s := range stmts {
 		if ec.walk(s) {
 			return true
@@ -54,9 +112,11 @@ func visit(pass *analysis.Pass, log zerolog.Logger, rule Rule, ident *ast.Ident,
 	return false
 }
 
-// walk returns true if the given if statement calls the rules expected method.
-func (ec *visitor) walk(stmt ast.Stmt) bool {
-	ast.Walk(ec, stmt)
+// walk returns true if the given node calls the rules expected method.
+func (ec *visitor) walk(node ast.Node) bool {
+	ec.found = false
+	ec.call, ec.expect, ec.ignored = nil, nil, false
+	ast.Walk(ec, node)
 
 	return ec.found
 }
@@ -72,19 +132,259 @@ func (ec *visitor) Visit(node ast.Node) (w ast.Visitor) {
 		return ec.visitCallExpr(t)
 	case *ast.AssignStmt:
 		return ec.visitAssignStmt(t)
+	case *ast.ValueSpec:
+		if len(t.Values) == 0 {
+			return ec // Declared without a value.
+		}
+		return ec.visitAssignStmt(assignment(t))
+	case *ast.ReturnStmt:
+		return ec.visitReturnStmt(t)
+	case *ast.FuncLit:
+		return ec.visitFuncLit(t)
+	case *ast.CompositeLit:
+		return ec.visitCompositeLit(t)
+	case *ast.ExprStmt:
+		ec.discards(t.X)
+		return ec
+	case *ast.DeferStmt:
+		ec.discards(t.Call)
+		return ec
+	case *ast.GoStmt:
+		ec.discards(t.Call)
+		return ec
 	default:
 		return ec
 	}
 }
 
+// transfers configures ec to treat returning an interested ident from
+// the function of type typ as transferring the obligation to the caller
+// and storing it in a field as transferring it to the fields owner.
+func (ec *visitor) transfers(typ *ast.FuncType) {
+	ec.transfer = true
+	ec.results = nil
+	if typ.Results == nil {
+		return
+	}
+
+	for _, f := range typ.Results.List {
+		ec.results = append(ec.results, f.Names...)
+	}
+}
+
+// visitReturnStmt checks if stmt returns one of the interested idents.
+// If so the obligation is transferred to the caller and it returns nil,
+// otherwise ec.
+func (ec *visitor) visitReturnStmt(stmt *ast.ReturnStmt) (w ast.Visitor) {
+	if !ec.transfer {
+		return ec
+	}
+
+	results := stmt.Results
+	if len(results) == 0 {
+		// Bare return of named results.
+		results = make([]ast.Expr, len(ec.results))
+		for i, ident := range ec.results {
+			results[i] = ident
+		}
+	}
+
+	for _, expr := range results {
+		ident, ok := astutil.Unparen(expr).(*ast.Ident)
+		if !ok {
+			continue // Not an ident.
+		}
+
+		if _, ok := ec.identObjs[ec.object(ident)]; ok {
+			ec.log.Debug().Stringer("ident", ident).Msg("returned")
+			ec.found = true
+			return nil
+		}
+	}
+
+	return ec
+}
+
+// visitFuncLit visits lit, returns from which don't transfer the obligation
+// as they return from lit not our function.
+func (ec *visitor) visitFuncLit(lit *ast.FuncLit) (w ast.Visitor) {
+	if !ec.transfer {
+		return ec
+	}
+
+	inner := *ec
+	inner.transfer = false
+	ast.Walk(&inner, lit.Body)
+	ec.found = inner.found
+
+	return nil
+}
+
 // visitAssignStmt visits stmt.
 func (ec *visitor) visitAssignStmt(stmt *ast.AssignStmt) (w ast.Visitor) {
+	ec.assignStmtDiscards(stmt)
 	ec.assignStmtMatches(stmt)
 	ec.assignStmtFuncLit(stmt)
+	if ec.assignStmtFields(stmt) {
+		return nil
+	}
+
+	return ec
+}
+
+// discards records expr as discarded if it's a call.
+func (ec *visitor) discards(expr ast.Expr) {
+	if call, ok := astutil.Unparen(expr).(*ast.CallExpr); ok {
+		ec.discarded[call] = struct{}{}
+	}
+}
+
+// assignStmtDiscards records the calls whose results stmt assigns
+// only to the blank identifier as discarded.
+func (ec *visitor) assignStmtDiscards(stmt *ast.AssignStmt) {
+	blank := func(expr ast.Expr) bool {
+		ident, ok := expr.(*ast.Ident)
+		return ok && ident.Name == "_"
+	}
+
+	if len(stmt.Lhs) != len(stmt.Rhs) {
+		// Tuple assignment.
+		for _, lhs := range stmt.Lhs {
+			if !blank(lhs) {
+				return
+			}
+		}
+		ec.discards(stmt.Rhs[0])
+		return
+	}
+
+	for i, rhs := range stmt.Rhs {
+		if blank(stmt.Lhs[i]) {
+			ec.discards(rhs)
+		}
+	}
+}
+
+// store represents an interested ident being stored in a field.
+type store struct {
+	// node is the assignment or composite literal element.
+	node ast.Node
+
+	// owner is the type which the field belongs to.
+	owner *types.Named
+
+	// field is the field the ident was stored in.
+	field *types.Var
+}
+
+// newStore returns the store of node to field of the struct type typ
+// and true if typ is a named struct, false otherwise.
+func newStore(node ast.Node, typ types.Type, field *types.Var) (store, bool) {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+
+	owner, ok := typ.(*types.Named)
+	if !ok || field == nil {
+		return store{}, false // Unnamed type has no methods.
+	}
+
+	return store{node: node, owner: owner, field: field}, true
+}
+
+// fieldStore returns the store for an assignment to expr and true if
+// it is a field, false otherwise.
+func fieldStore(info *types.Info, expr ast.Expr) (store, bool) {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return store{}, false
+	}
+
+	selection, ok := info.Selections[sel]
+	if !ok || selection.Kind() != types.FieldVal {
+		return store{}, false // Not a field.
+	}
+
+	field, _ := selection.Obj().(*types.Var)
+
+	return newStore(sel, selection.Recv(), field)
+}
+
+// assignStmtFields checks stmt for assignments of interested idents to
+// fields. If any are found they are registered in ec.stores and it
+// returns true, otherwise false.
+func (ec *visitor) assignStmtFields(stmt *ast.AssignStmt) bool {
+	if !ec.transfer || len(stmt.Lhs) != len(stmt.Rhs) {
+		return false
+	}
+
+	for i, rhs := range stmt.Rhs {
+		if !ec.interested(rhs) {
+			continue
+		}
+
+		if st, ok := fieldStore(ec.pass.TypesInfo, stmt.Lhs[i]); ok {
+			ec.stores = append(ec.stores, st)
+			ec.found = true
+		}
+	}
+
+	return ec.found
+}
+
+// visitCompositeLit checks lit for interested idents stored in fields.
+// If any are found they are registered in ec.stores and it returns nil,
+// otherwise ec.
+func (ec *visitor) visitCompositeLit(lit *ast.CompositeLit) (w ast.Visitor) {
+	if !ec.transfer {
+		return ec
+	}
+
+	typ := ec.pass.TypesInfo.TypeOf(lit)
+	if typ == nil {
+		return ec // Unknown type.
+	}
+
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return ec // Not a struct.
+	}
+
+	for i, elt := range lit.Elts {
+		field := st.Field(i)
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			elt = kv.Value
+			field = structField(st, kv.Key)
+		}
+
+		if !ec.interested(elt) {
+			continue
+		}
+
+		if s, ok := newStore(elt, typ, field); ok {
+			ec.stores = append(ec.stores, s)
+			ec.found = true
+		}
+	}
+
+	if ec.found {
+		return nil
+	}
 
 	return ec
 }
 
+// interested returns true if expr is one of the interested idents, false otherwise.
+func (ec *visitor) interested(expr ast.Expr) bool {
+	ident, ok := astutil.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	_, ok = ec.identObjs[ec.object(ident)]
+	return ok
+}
+
 // containsType returns true if expr represents on of our expected types, false otherwise.
 func (ec *visitor) containsType(expr ast.Expr) bool {
 	tv, ok := ec.pass.TypesInfo.Types[expr]
@@ -92,7 +392,7 @@ func (ec *visitor) containsType(expr ast.Expr) bool {
 		return false // Unknown type.
 	}
 
-	return containsType(tv.Type, ec.rule.expectedTypes)
+	return ec.rule.expects.match(tv.Type)
 }
 
 // dump dumps the details of node.
@@ -118,18 +418,23 @@ func (ec *visitor) assignStmtFuncLit(stmt *ast.AssignStmt) {
 			continue // Assigned not ident.
 		}
 
+		obj := ec.object(ident)
+		if obj == nil {
+			continue // Blank identifier.
+		}
+
 		for _, f := range lit.Type.Params.List {
 			if !ec.containsType(f.Type) {
 				continue // Not an expected parameter.
 			}
 
 			for j, param := range f.Names {
-				if visit(ec.pass, ec.log, ec.rule, param, lit.Body.List) {
+				if visit(ec.pass, ec.log, ec.facts, ec.rule, param, lit.Body.List) {
 					// Rule matched call for this parameter.
-					args := ec.calledArgs[ident.Obj]
+					args := ec.calledArgs[obj]
 					if args == nil {
 						args = make(map[int]struct{})
-						ec.calledArgs[ident.Obj] = args
+						ec.calledArgs[obj] = args
 					}
 					args[j] = struct{}{}
 				}
@@ -147,7 +452,7 @@ func (ec *visitor) assignStmtMatches(stmt *ast.AssignStmt) {
 			continue // Not an Ident.
 		}
 
-		if _, ok := ec.identObjs[identRHS.Obj]; ok {
+		if _, ok := ec.identObjs[ec.object(identRHS)]; ok {
 			// Right hand side matches.
 			lhs := stmt.Lhs[i]
 			identLHS, ok := lhs.(*ast.Ident)
@@ -155,14 +460,30 @@ func (ec *visitor) assignStmtMatches(stmt *ast.AssignStmt) {
 				continue // Not an Ident.
 			}
 
+			obj := ec.object(identLHS)
+			if obj == nil {
+				// Blank identifier, which has no object so tracking
+				// it would match any other ident without one.
+				continue
+			}
+
 			// Assignment match found.
-			ec.identObjs[identLHS.Obj] = struct{}{}
+			ec.identObjs[obj] = struct{}{}
 		}
 	}
 }
 
 // visitCallExpr visits call.
 func (ec *visitor) visitCallExpr(call *ast.CallExpr) (w ast.Visitor) {
+	if ec.releasedArg(call) {
+		ec.found = true
+		return nil // Expected function was called by callee.
+	}
+
+	if ec.visitFuncCall(call) == nil {
+		return nil // Expected function was called.
+	}
+
 	switch t := call.Fun.(type) {
 	case *ast.SelectorExpr:
 		return ec.visitCallNode(call, t)
@@ -173,6 +494,38 @@ func (ec *visitor) visitCallExpr(call *ast.CallExpr) (w ast.Visitor) {
 	}
 }
 
+// releasedArg returns true if call passes one of the interested idents
+// to a function which always calls the expected method on it, false
+// otherwise.
+func (ec *visitor) releasedArg(call *ast.CallExpr) bool {
+	if ec.facts == nil {
+		return false
+	}
+
+	fn := typeutil.StaticCallee(ec.pass.TypesInfo, call)
+	if fn == nil {
+		return false // Dynamic call or builtin.
+	}
+
+	fact := ec.facts.releases(fn)
+	if fact == nil {
+		return false // Releases nothing.
+	}
+
+	for i, expr := range call.Args {
+		arg, ok := expr.(*ast.Ident)
+		if !ok {
+			continue // Not an ident arg.
+		}
+
+		if _, ok := ec.identObjs[ec.object(arg)]; ok && fact.released(ec.rule.id, i) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // visitCallIdent checks if call resulted in any of interested idents having
 // the expected call called.
 // If a match was found it returns nil, otherwise ec.
@@ -187,13 +540,13 @@ func (ec *visitor) visitCallIdent(call *ast.CallExpr, ident *ast.Ident) (w ast.V
 			continue // Not an ident arg.
 		}
 
-		for obj := range ec.identObjs {
-			if arg.Obj == obj {
-				if _, ok := ec.calledArgs[ident.Obj][i]; ok {
-					ec.found = true
-					return nil // Expected function was called.
-				}
-			}
+		if _, ok := ec.identObjs[ec.object(arg)]; !ok {
+			continue // Not an interested ident.
+		}
+
+		if _, ok := ec.calledArgs[ec.object(ident)][i]; ok {
+			ec.found = true
+			return nil // Expected function was called.
 		}
 	}
 
@@ -208,8 +561,18 @@ func (ec *visitor) visitCallNode(call *ast.CallExpr, node ast.Node) (w ast.Visit
 		return ec
 	}
 
-	name := strings.Join(parts[1:], ".")
-	matches := ec.rule.matchesCall(call, name)
+	// The call must be on the field of the interested idents if set.
+	depth := 0
+	if ec.field != "" {
+		if len(parts) < 2 || parts[1] != ec.field {
+			return ec // Not our field.
+		}
+		depth = 1
+	}
+
+	name := strings.Join(parts[depth+1:], ".")
+	calls := ec.rule.matchingCalls(call, name)
+	matches := len(calls) != 0
 	ec.log.Debug().
 		Bool("matches", matches).
 		Str("call", name).
@@ -220,26 +583,200 @@ func (ec *visitor) visitCallNode(call *ast.CallExpr, node ast.Node) (w ast.Visit
 	}
 
 	ident := rootIdent(node)
-	typ, ok := ec.pass.TypesInfo.Types[ident]
+	typ, ok := ec.pass.TypesInfo.Types[selectorAt(node, depth)]
 	if !ok {
 		return ec // Unknown type
 	}
 
-	if name == "" {
-		name = typ.Type.String()
-	} else {
-		name = typ.Type.String() + "." + name
-	}
-	if _, ok := ec.rule.expectedCalls[name]; !ok {
+	if !ec.rule.expects.match(typ.Type) {
 		return ec // Type doesn't match.
 	}
 
-	if _, ok := ec.identObjs[ident.Obj]; !ok {
+	if _, ok := ec.identObjs[ec.object(ident)]; !ok {
 		return ec // Call receiver didn't match an expected objects.
 	}
 
+	e := ec.matchedCall(call, calls)
+	if e == nil {
+		return ec // Arguments don't match.
+	}
+
+	return ec.called(call, e)
+}
+
+// visitFuncCall checks if call was a call to an expected function passed
+// our interested variable.
+func (ec *visitor) visitFuncCall(call *ast.CallExpr) (w ast.Visitor) {
+	fn := typeutil.StaticCallee(ec.pass.TypesInfo, call)
+	if fn == nil {
+		return ec // Dynamic call or builtin.
+	}
+
+	e := ec.matchedCall(call, ec.rule.matchingFuncs(call, fn))
+	if e == nil {
+		return ec // Doesn't match function or args.
+	}
+
+	return ec.called(call, e)
+}
+
+// called records call as the expected call e. If its result must be
+// checked and was discarded it returns ec, otherwise nil.
+func (ec *visitor) called(call *ast.CallExpr, e *Expect) (w ast.Visitor) {
+	if _, ok := ec.discarded[call]; ok && e.CheckResult {
+		ec.call, ec.expect, ec.ignored = call, e, true
+		return ec // Result must be checked.
+	}
+
 	// Expected function was called.
 	ec.found = true
+	ec.call, ec.expect, ec.ignored = call, e, false
 
 	return nil
 }
+
+// references records the objects the results of the triggering call
+// in stmt are assigned to, so they can be matched by $N arguments.
+func (ec *visitor) references(stmt *ast.AssignStmt) {
+	if len(stmt.Rhs) != 1 {
+		return // Not a tuple assignment.
+	}
+
+	ec.refs = make(map[int]types.Object, len(stmt.Lhs))
+	for i, lhs := range stmt.Lhs {
+		if ident, ok := lhs.(*ast.Ident); ok {
+			if obj := ec.object(ident); obj != nil {
+				ec.refs[i] = obj
+			}
+		}
+	}
+}
+
+// arguments records the objects passed as the arguments of the triggering
+// call, so they can be matched by @N arguments.
+func (ec *visitor) arguments(call *ast.CallExpr) {
+	ec.args = make(map[int]types.Object, len(call.Args))
+	for i, arg := range call.Args {
+		if ident, ok := astutil.Unparen(arg).(*ast.Ident); ok {
+			if obj := ec.object(ident); obj != nil {
+				ec.args[i] = obj
+			}
+		}
+	}
+}
+
+// matchedCall returns the first of calls whose arguments match those of
+// call, nil if there is none.
+func (ec *visitor) matchedCall(call *ast.CallExpr, calls []*Expect) *Expect {
+	for _, e := range calls {
+		if ec.matchesArgs(call, e) {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// methodCall returns the first call in node of method on one of the
+// interested idents, nil if there is none.
+func (ec *visitor) methodCall(node ast.Node, method string) *ast.CallExpr {
+	var found *ast.CallExpr
+	ast.Inspect(node, func(n ast.Node) bool {
+		if found != nil {
+			return false
+		}
+
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		parts := names(call.Fun)
+		depth := 0
+		if ec.field != "" {
+			if len(parts) < 2 || parts[1] != ec.field {
+				return true // Not our field.
+			}
+			depth = 1
+		}
+
+		if len(parts) <= depth+1 || "."+strings.Join(parts[depth+1:], ".") != method {
+			return true // Not the method.
+		}
+
+		if _, ok := ec.identObjs[ec.object(rootIdent(call.Fun))]; ok {
+			found = call
+		}
+
+		return found == nil
+	})
+
+	return found
+}
+
+// matchesArgs returns true if the arguments of call match the arguments
+// expected by e, false otherwise.
+func (ec *visitor) matchesArgs(call *ast.CallExpr, e *Expect) bool {
+	for i, m := range e.args {
+		arg := astutil.Unparen(call.Args[i])
+		switch m.kind {
+		case argAny:
+		case argResult, argArg:
+			if ec.rule.self(m) {
+				if !ec.interested(arg) {
+					return false
+				}
+				continue
+			}
+
+			obj, known := ec.refs[m.result]
+			if m.kind == argArg {
+				obj, known = ec.args[m.arg]
+			}
+
+			ident, ok := arg.(*ast.Ident)
+			if !ok || !known || ec.object(ident) != obj {
+				return false
+			}
+		case argNil:
+			tv, ok := ec.pass.TypesInfo.Types[arg]
+			if !ok || !tv.IsNil() {
+				return false
+			}
+		case argValue:
+			tv, ok := ec.pass.TypesInfo.Types[arg]
+			if !ok || tv.Value == nil || !sameValue(tv.Value, m.value) {
+				return false
+			}
+		case argType:
+			t := ec.pass.TypesInfo.TypeOf(arg)
+			if t == nil {
+				return false
+			}
+
+			if typ := lookupTypeExpr(ec.pass.Pkg, m.typ); typ != nil {
+				if !types.Identical(unalias(t), unalias(typ)) {
+					return false
+				}
+			} else if types.TypeString(t, nil) != m.typ {
+				return false // Not a named type, for example map[string]int.
+			}
+		}
+	}
+
+	return true
+}
+
+// sameValue returns true if the constants x and y are equal, false
+// otherwise, including if they are of incomparable kinds.
+func sameValue(x, y constant.Value) bool {
+	numeric := func(k constant.Kind) bool {
+		return k == constant.Int || k == constant.Float || k == constant.Complex
+	}
+
+	if x.Kind() != y.Kind() && (!numeric(x.Kind()) || !numeric(y.Kind())) {
+		return false
+	}
+
+	return constant.Compare(x, token.EQL, y)
+}